@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"sort"
+
+	"db/i18n"
+	"db/tree"
+)
+
+// Агрегатные функции.
+const (
+	AggregateCount = "count"
+	AggregateSum   = "sum"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateAvg   = "avg"
+)
+
+// AggregateGroup результат агрегатной функции для одной группы записей.
+// Group — значение поля группировки (nil без группировки), Count — число
+// записей, учтенных функцией, Value — результат функции.
+type AggregateGroup struct {
+	Group interface{}
+	Count int64
+	Value interface{}
+}
+
+// accumulator накапливает значения функции по мере обхода коллекции.
+type accumulator struct {
+	group    interface{}
+	count    int64
+	numbers  int64
+	sumInt   int64
+	sumFloat float64
+	isFloat  bool
+	extreme  interface{}
+}
+
+// Aggregate вычисляет функцию fn по полю field записей коллекции, группируя их
+// по полю groupBy (пустая строка — без группировки). Поле "*" в count означает
+// подсчет всех записей. sum и avg учитывают только числа, min и max — любые
+// значения, кроме null, в порядке вторичного индекса; записи без поля пропускаются.
+// Коллекция обходится один раз через ForEach, без копирования записей.
+func Aggregate(collection tree.Collection, fn, field, groupBy string) ([]AggregateGroup, error) {
+	switch fn {
+	case AggregateCount, AggregateSum, AggregateMin, AggregateMax, AggregateAvg:
+	default:
+		return nil, i18n.Errorf(i18n.UnknownAggregate, fn)
+	}
+	if field == "*" && fn != AggregateCount {
+		return nil, i18n.Errorf(i18n.StarOnlyForCount)
+	}
+
+	groups := make(map[string]*accumulator)
+	collection.ForEach(func(key string, value interface{}) bool {
+		var group interface{}
+		if groupBy != "" {
+			group, _ = lookupField(key, value, groupBy)
+		}
+		groupKey := encodeIndexValue(group)
+		acc, exists := groups[groupKey]
+		if !exists {
+			acc = &accumulator{group: group}
+			groups[groupKey] = acc
+		}
+		if field == "*" {
+			acc.count++
+			return true
+		}
+		if fv, ok := lookupField(key, value, field); ok && fv != nil {
+			acc.add(fn, fv)
+		}
+		return true
+	})
+
+	keys := make([]string, 0, len(groups))
+	for groupKey := range groups {
+		keys = append(keys, groupKey)
+	}
+	sort.Strings(keys)
+	result := make([]AggregateGroup, 0, len(keys))
+	for _, groupKey := range keys {
+		acc := groups[groupKey]
+		result = append(result, AggregateGroup{Group: acc.group, Count: acc.count, Value: acc.result(fn)})
+	}
+	if len(result) == 0 && groupBy == "" {
+		result = append(result, AggregateGroup{Value: (&accumulator{}).result(fn)})
+	}
+	return result, nil
+}
+
+func (acc *accumulator) add(fn string, value interface{}) {
+	switch fn {
+	case AggregateSum, AggregateAvg:
+		switch v := value.(type) {
+		case int64:
+			acc.sumInt += v
+		case int:
+			acc.sumInt += int64(v)
+		case float64:
+			acc.sumFloat += v
+			acc.isFloat = true
+		default:
+			return
+		}
+		acc.numbers++
+	case AggregateMin:
+		if acc.count == 0 || compareIndexValues(value, acc.extreme) < 0 {
+			acc.extreme = value
+		}
+	case AggregateMax:
+		if acc.count == 0 || compareIndexValues(value, acc.extreme) > 0 {
+			acc.extreme = value
+		}
+	}
+	acc.count++
+}
+
+func (acc *accumulator) result(fn string) interface{} {
+	switch fn {
+	case AggregateCount:
+		return acc.count
+	case AggregateSum:
+		if acc.isFloat {
+			return acc.sumFloat + float64(acc.sumInt)
+		}
+		return acc.sumInt
+	case AggregateAvg:
+		if acc.numbers == 0 {
+			return nil
+		}
+		return (acc.sumFloat + float64(acc.sumInt)) / float64(acc.numbers)
+	default:
+		return acc.extreme
+	}
+}
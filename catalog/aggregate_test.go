@@ -0,0 +1,116 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"db/i18n"
+	"db/tree"
+)
+
+// aggregateRecords записи с полем группировки g и числовым полем n, в том
+// числе без полей, со значениями null и не являющиеся объектами.
+var aggregateRecords = map[string]string{
+	"a": `{"g":"x","n":1}`,
+	"b": `{"g":"x","n":2.5}`,
+	"c": `{"g":"x","n":"s"}`,
+	"d": `{"g":"y","n":4}`,
+	"e": `{"g":"y"}`,
+	"f": `{"g":null,"n":-1}`,
+	"g": `{"n":10}`,
+	"h": `{"g":1,"n":3}`,
+	"i": `{"g":1.0,"n":null}`,
+	"j": `7`,
+}
+
+func TestAggregate(t *testing.T) {
+	collection := tree.NewTreeCollection("avl")
+	for key, text := range aggregateRecords {
+		value, err := ParseValue(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := collection.Insert(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Группы упорядочены как значения индекса: null, числа, строки;
+	// записи без поля группировки попадают в группу null, 1 и 1.0 — одна группа
+	groups := func(values ...interface{}) []AggregateGroup {
+		result := []AggregateGroup{}
+		for i, group := range []interface{}{nil, int64(1), "x", "y"} {
+			result = append(result, AggregateGroup{Group: group, Count: values[2*i].(int64), Value: values[2*i+1]})
+		}
+		return result
+	}
+	tests := []struct {
+		fn, field, groupBy string
+		want               []AggregateGroup
+	}{
+		{"count", "*", "", []AggregateGroup{{Count: 10, Value: int64(10)}}},
+		{"count", "n", "", []AggregateGroup{{Count: 7, Value: int64(7)}}},
+		{"sum", "n", "", []AggregateGroup{{Count: 6, Value: 19.5}}},
+		{"avg", "n", "", []AggregateGroup{{Count: 6, Value: 3.25}}},
+		{"min", "n", "", []AggregateGroup{{Count: 7, Value: int64(-1)}}},
+		{"max", "n", "", []AggregateGroup{{Count: 7, Value: "s"}}},
+		{"count", "missing", "", []AggregateGroup{{Count: 0, Value: int64(0)}}},
+		{"sum", "missing", "", []AggregateGroup{{Count: 0, Value: int64(0)}}},
+		{"avg", "missing", "", []AggregateGroup{{Count: 0, Value: nil}}},
+		{"max", "missing", "", []AggregateGroup{{Count: 0, Value: nil}}},
+
+		{"count", "*", "g", groups(int64(3), int64(3), int64(2), int64(2), int64(3), int64(3), int64(2), int64(2))},
+		{"count", "n", "g", groups(int64(2), int64(2), int64(1), int64(1), int64(3), int64(3), int64(1), int64(1))},
+		{"sum", "n", "g", groups(int64(2), int64(9), int64(1), int64(3), int64(2), 3.5, int64(1), int64(4))},
+		{"avg", "n", "g", groups(int64(2), 4.5, int64(1), 3.0, int64(2), 1.75, int64(1), 4.0)},
+		{"min", "n", "g", groups(int64(2), int64(-1), int64(1), int64(3), int64(3), int64(1), int64(1), int64(4))},
+		{"max", "n", "g", groups(int64(2), int64(10), int64(1), int64(3), int64(3), "s", int64(1), int64(4))},
+		{"sum", "missing", "g", groups(int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0))},
+		{"count", "*", "_key", func() []AggregateGroup {
+			var result []AggregateGroup
+			for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+				result = append(result, AggregateGroup{Group: key, Count: 1, Value: int64(1)})
+			}
+			return result
+		}()},
+	}
+	for _, test := range tests {
+		got, err := Aggregate(collection, test.fn, test.field, test.groupBy)
+		if err != nil {
+			t.Errorf("%s(%s) по %q: %v", test.fn, test.field, test.groupBy, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s(%s) по %q: %#v, ожидалось %#v", test.fn, test.field, test.groupBy, got, test.want)
+		}
+	}
+}
+
+func TestAggregateEmptyAndErrors(t *testing.T) {
+	empty := tree.NewTreeCollection("avl")
+	if got, err := Aggregate(empty, "count", "*", ""); err != nil ||
+		!reflect.DeepEqual(got, []AggregateGroup{{Value: int64(0)}}) {
+		t.Errorf("count(*) пустой коллекции: %#v (%v)", got, err)
+	}
+	if got, err := Aggregate(empty, "count", "*", "g"); err != nil || len(got) != 0 {
+		t.Errorf("count(*) по g пустой коллекции: %#v (%v)", got, err)
+	}
+
+	tests := []struct {
+		fn, field string
+		err       i18n.ID
+	}{
+		{"median", "n", i18n.UnknownAggregate},
+		{"", "n", i18n.UnknownAggregate},
+		{"COUNT", "*", i18n.UnknownAggregate},
+		{"sum", "*", i18n.StarOnlyForCount},
+		{"avg", "*", i18n.StarOnlyForCount},
+		{"min", "*", i18n.StarOnlyForCount},
+		{"max", "*", i18n.StarOnlyForCount},
+	}
+	for _, test := range tests {
+		if _, err := Aggregate(empty, test.fn, test.field, ""); !hasMessage(err, test.err) {
+			t.Errorf("%s(%s): ошибка %v, ожидалась %s", test.fn, test.field, err, test.err)
+		}
+	}
+}
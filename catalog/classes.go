@@ -0,0 +1,321 @@
+// Package catalog содержит каталог данных: пулы, схемы и коллекции с историей
+// изменений, вторичными индексами, описаниями полей, запросами, транзакциями
+// и контрольными точками на диске.
+package catalog
+
+import (
+	"sync"
+
+	"db/i18n"
+	"db/tree"
+)
+
+// Ошибки поиска и добавления элементов каталога; проверяются через errors.Is.
+var (
+	ErrPoolNotFound       = i18n.Errorf(i18n.PoolNotFound)
+	ErrSchemaNotFound     = i18n.Errorf(i18n.SchemaNotFound)
+	ErrCollectionNotFound = i18n.Errorf(i18n.CollectionNotFound)
+	ErrPoolExists         = i18n.Errorf(i18n.PoolExists)
+	ErrSchemaExists       = i18n.Errorf(i18n.SchemaExists)
+	ErrCollectionExists   = i18n.Errorf(i18n.CollectionExists)
+	ErrKeyNotFound        = tree.ErrKeyNotFound
+	ErrDuplicateKey       = tree.ErrDuplicateKey
+)
+
+// Блокировки иерархии берутся сверху вниз: AllPools, Pool, Schema, коллекция.
+// Каждый уровень защищает только собственную карту, поэтому операции над
+// разными коллекциями не ждут друг друга.
+
+// Пулы, схемы и коллекции разделяют часы фиксации AllPools, в который они
+// добавлены (см. mvcc.go); при добавлении готового элемента часы передаются вниз.
+
+type Pool struct {
+	mu      sync.RWMutex // защищает schema и commits
+	schema  map[string]*Schema
+	commits *commitClock
+}
+
+type AllPools struct {
+	mu      sync.RWMutex // защищает pools
+	pools   map[string]*Pool
+	commits *commitClock
+}
+
+
+func InitPool() *AllPools {
+	return &AllPools{
+		pools:   make(map[string]*Pool),
+		commits: newCommitClock(),
+	}
+}
+
+func (pools *AllPools) AddPool(name string) error {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	if _, exists := pools.pools[name]; exists {
+		return ErrPoolExists
+	}
+	pool := NewPool()
+	pool.commits = pools.commits
+	pools.pools[name] = pool
+	return nil
+}
+
+func (pools *AllPools) RemovePool(name string) error {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	pool, exists := pools.pools[name]
+	if !exists {
+		return ErrPoolNotFound
+	}
+	// Удаляем все схемы и их коллекции
+	for _, schemaName := range pool.SchemaNames() {
+		// Удаляем схему вместе с ее коллекциями
+		pool.RemoveSchema(schemaName)
+	}
+	// Удаляем пул
+	delete(pools.pools, name)
+	return nil
+}
+
+
+func (pools *AllPools) GetPool(name string) (*Pool, error) {
+	pools.mu.RLock()
+	defer pools.mu.RUnlock()
+	returnEl, ok := pools.pools[name]
+	if !ok {
+		return nil, ErrPoolNotFound
+	}
+	return returnEl, nil
+}
+
+// AttachPool добавляет готовый пул, например восстановленный при отмене удаления.
+func (pools *AllPools) AttachPool(name string, pool *Pool) error {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	if _, exists := pools.pools[name]; exists {
+		return ErrPoolExists
+	}
+	pool.setCommits(pools.commits)
+	pools.pools[name] = pool
+	return nil
+}
+
+// PoolNames возвращает имена пулов.
+func (pools *AllPools) PoolNames() []string {
+	pools.mu.RLock()
+	defer pools.mu.RUnlock()
+	names := make([]string, 0, len(pools.pools))
+	for name := range pools.pools {
+		names = append(names, name)
+	}
+	return names
+}
+
+func NewPool() *Pool {
+	return &Pool{
+		schema:  make(map[string]*Schema),
+		commits: newCommitClock(),
+	}
+}
+
+// setCommits переводит пул со всеми схемами и коллекциями на часы commits.
+func (pool *Pool) setCommits(commits *commitClock) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.commits = commits
+	for _, schema := range pool.schema {
+		schema.setCommits(commits)
+	}
+}
+
+func (pool *Pool) GetSchema(schemaName string) (*Schema, error) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	returnEl, ok := pool.schema[schemaName]
+	if !ok {
+		return nil, ErrSchemaNotFound
+	}
+	return returnEl, nil
+}
+
+func (pool *Pool) AddSchema(name string) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if _, exists := pool.schema[name]; exists {
+		return ErrSchemaExists
+	}
+	schema := InitSchema()
+	schema.commits = pool.commits
+	pool.schema[name] = schema
+	return nil
+}
+
+func (pool *Pool) RemoveSchema(name string) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	schema, exists := pool.schema[name]
+	if !exists {
+		return ErrSchemaNotFound
+	}
+	// Удаляем все коллекции в схеме
+	for _, collectionName := range schema.CollectionNames() {
+		schema.RemoveCollection(collectionName)
+	}
+	// Удаляем схему из пула
+	delete(pool.schema, name)
+	return nil
+}
+
+// AttachSchema добавляет готовую схему, например восстановленную при отмене удаления.
+func (pool *Pool) AttachSchema(name string, schema *Schema) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if _, exists := pool.schema[name]; exists {
+		return ErrSchemaExists
+	}
+	schema.setCommits(pool.commits)
+	pool.schema[name] = schema
+	return nil
+}
+
+// SchemaNames возвращает имена схем пула.
+func (pool *Pool) SchemaNames() []string {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	names := make([]string, 0, len(pool.schema))
+	for name := range pool.schema {
+		names = append(names, name)
+	}
+	return names
+}
+
+type Schema struct {
+	mu         sync.RWMutex // защищает collection и commits
+	collection map[string]*VersionedCollection
+	commits    *commitClock
+}
+
+func InitSchema() *Schema {
+	return &Schema{
+		collection: make(map[string]*VersionedCollection),
+		commits:    newCommitClock(),
+	}
+}
+
+// setCommits переводит схему со всеми коллекциями на часы commits.
+func (schema *Schema) setCommits(commits *commitClock) {
+	schema.mu.Lock()
+	defer schema.mu.Unlock()
+	schema.commits = commits
+	for _, collection := range schema.collection {
+		collection.setCommits(commits)
+	}
+}
+
+func (schema *Schema) GetCollection(name string) (tree.Collection, error) {
+	schema.mu.RLock()
+	defer schema.mu.RUnlock()
+	returnEl, ok := schema.collection[name]
+	if !ok {
+		return nil, ErrCollectionNotFound
+	}
+	return returnEl, nil
+}
+
+// GetVersionedCollection возвращает коллекцию вместе с историей ее изменений.
+func (schema *Schema) GetVersionedCollection(name string) (*VersionedCollection, error) {
+	schema.mu.RLock()
+	defer schema.mu.RUnlock()
+	returnEl, ok := schema.collection[name]
+	if !ok {
+		return nil, ErrCollectionNotFound
+	}
+	return returnEl, nil
+}
+
+// AddCollection добавляет коллекцию в схему; все изменения коллекции
+// после добавления записываются в ее историю.
+func (schema *Schema) AddCollection(name string, collection tree.Collection) error {
+	schema.mu.Lock()
+	defer schema.mu.Unlock()
+	if _, exists := schema.collection[name]; exists {
+		return ErrCollectionExists
+	}
+	versioned, ok := collection.(*VersionedCollection)
+	if !ok {
+		versioned = NewVersionedCollection(collection)
+	}
+	versioned.setCommits(schema.commits)
+	schema.collection[name] = versioned
+	return nil
+}
+
+func (schema *Schema) RemoveCollection(name string) error {
+	schema.mu.Lock()
+	defer schema.mu.Unlock()
+	if _, exists := schema.collection[name]; !exists {
+		return ErrCollectionNotFound
+	}
+	delete(schema.collection, name)
+	return nil
+}
+
+// AttachCollection добавляет готовую коллекцию вместе с ее историей,
+// например восстановленную при отмене удаления.
+func (schema *Schema) AttachCollection(name string, collection *VersionedCollection) error {
+	schema.mu.Lock()
+	defer schema.mu.Unlock()
+	if _, exists := schema.collection[name]; exists {
+		return ErrCollectionExists
+	}
+	collection.setCommits(schema.commits)
+	schema.collection[name] = collection
+	return nil
+}
+
+// CollectionNames возвращает имена коллекций схемы.
+func (schema *Schema) CollectionNames() []string {
+	schema.mu.RLock()
+	defer schema.mu.RUnlock()
+	names := make([]string, 0, len(schema.collection))
+	for name := range schema.collection {
+		names = append(names, name)
+	}
+	return names
+}
+
+// LookupCollection возвращает коллекцию по пути пул/схема/коллекция.
+func (pools *AllPools) LookupCollection(poolName, schemaName, collectionName string) (*VersionedCollection, error) {
+	pool, err := pools.GetPool(poolName)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := pool.GetSchema(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	return schema.GetVersionedCollection(collectionName)
+}
+
+// Clone возвращает копию схемы с теми же коллекциями.
+func (schema *Schema) Clone() *Schema {
+	schema.mu.RLock()
+	defer schema.mu.RUnlock()
+	copied := InitSchema()
+	for name, collection := range schema.collection {
+		copied.collection[name] = collection
+	}
+	return copied
+}
+
+// Clone возвращает копию пула с копиями его схем.
+func (pool *Pool) Clone() *Pool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	copied := NewPool()
+	for name, schema := range pool.schema {
+		copied.schema[name] = schema.Clone()
+	}
+	return copied
+}
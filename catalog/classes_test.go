@@ -0,0 +1,176 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"db/tree"
+)
+
+// Тесты рассчитаны на запуск с детектором гонок: go test -race ./catalog
+
+var collectionTypes = []string{"map", "avl", "btree", "redblack"}
+
+func TestConcurrentRecordsAcrossCollections(t *testing.T) {
+	pools := InitPool()
+	const poolCount, schemaCount, collectionCount = 2, 2, 2
+	type path struct{ pool, schema, collection string }
+	var paths []path
+	for p := 0; p < poolCount; p++ {
+		poolName := fmt.Sprintf("p%d", p)
+		if err := pools.AddPool(poolName); err != nil {
+			t.Fatal(err)
+		}
+		pool, _ := pools.GetPool(poolName)
+		for s := 0; s < schemaCount; s++ {
+			schemaName := fmt.Sprintf("s%d", s)
+			if err := pool.AddSchema(schemaName); err != nil {
+				t.Fatal(err)
+			}
+			schema, _ := pool.GetSchema(schemaName)
+			for c := 0; c < collectionCount; c++ {
+				collectionName := fmt.Sprintf("c%d", c)
+				// Коллекции всех типов: map, avl, btree, redblack
+				collection, err := tree.NewCollection(collectionTypes[len(paths)%len(collectionTypes)])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := schema.AddCollection(collectionName, collection); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, path{poolName, schemaName, collectionName})
+			}
+		}
+	}
+
+	const workersPerCollection, recordCount = 3, 100
+	var wg sync.WaitGroup
+	for _, target := range paths {
+		for w := 0; w < workersPerCollection; w++ {
+			wg.Add(1)
+			go func(target path, w int) {
+				defer wg.Done()
+				for i := 0; i < recordCount; i++ {
+					coll, err := pools.LookupCollection(target.pool, target.schema, target.collection)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					key := fmt.Sprintf("w%d-%03d", w, i)
+					if err := coll.Insert(key, int64(i)); err != nil {
+						t.Error(err)
+						return
+					}
+					if value, err := coll.Get(key); err != nil || value != int64(i) {
+						t.Errorf("%v %s: %v (%v)", target, key, value, err)
+						return
+					}
+					// Чтения целой коллекции идут вперемешку с изменениями
+					coll.GetRangeRecords("w", "x")
+					coll.ForEach(func(string, interface{}) bool { return true })
+					if i%2 == 0 {
+						if err := coll.Remove(key); err != nil {
+							t.Error(err)
+							return
+						}
+					} else if err := coll.Update(key, int64(-i)); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(target, w)
+		}
+		// Читатели срезов и истории работают параллельно с писателями
+		wg.Add(1)
+		go func(target path) {
+			defer wg.Done()
+			for i := 0; i < recordCount; i++ {
+				snapshot := pools.Snapshot()
+				if _, err := snapshot.Records(target.pool, target.schema, target.collection); err != nil {
+					t.Error(err)
+					return
+				}
+				coll, _ := pools.LookupCollection(target.pool, target.schema, target.collection)
+				coll.History().Versions(fmt.Sprintf("w0-%03d", i))
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	for _, target := range paths {
+		coll, _ := pools.LookupCollection(target.pool, target.schema, target.collection)
+		records, err := coll.GetRangeRecords("", "\xff")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Четные ключи удалены, нечетные обновлены
+		if want := workersPerCollection * recordCount / 2; len(records) != want {
+			t.Fatalf("%v: %d записей, ожидалось %d", target, len(records), want)
+		}
+		for _, record := range records {
+			if value, ok := record.Value.(int64); !ok || value >= 0 {
+				t.Fatalf("%v: запись %s = %v не обновлена", target, record.Key, record.Value)
+			}
+		}
+	}
+}
+
+func TestConcurrentAddRemoveStructure(t *testing.T) {
+	pools := InitPool()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				// Клиенты одновременно создают и удаляют одни и те же имена
+				poolName := fmt.Sprintf("p%d", i%3)
+				if err := pools.AddPool(poolName); err != nil && !errors.Is(err, ErrPoolExists) {
+					t.Error(err)
+					return
+				}
+				pool, err := pools.GetPool(poolName)
+				if err != nil {
+					continue
+				}
+				schemaName := fmt.Sprintf("s%d", w%2)
+				if err := pool.AddSchema(schemaName); err != nil && !errors.Is(err, ErrSchemaExists) {
+					t.Error(err)
+					return
+				}
+				schema, err := pool.GetSchema(schemaName)
+				if err != nil {
+					continue
+				}
+				if err := schema.AddCollection("c", tree.NewMapCollection()); err != nil && !errors.Is(err, ErrCollectionExists) {
+					t.Error(err)
+					return
+				}
+				if coll, err := pools.LookupCollection(poolName, schemaName, "c"); err == nil {
+					coll.Insert(fmt.Sprintf("k%d", w), int64(i))
+					coll.Get(fmt.Sprintf("k%d", w))
+				}
+				pools.PoolNames()
+				pool.SchemaNames()
+				schema.CollectionNames()
+				switch i % 3 {
+				case 0:
+					if err := schema.RemoveCollection("c"); err != nil && !errors.Is(err, ErrCollectionNotFound) {
+						t.Error(err)
+					}
+				case 1:
+					if err := pool.RemoveSchema(schemaName); err != nil && !errors.Is(err, ErrSchemaNotFound) {
+						t.Error(err)
+					}
+				default:
+					if err := pools.RemovePool(poolName); err != nil && !errors.Is(err, ErrPoolNotFound) {
+						t.Error(err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
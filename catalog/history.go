@@ -0,0 +1,286 @@
+package catalog
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"db/tree"
+)
+
+// SetClock задает источник времени для новых версий пулов, например время
+// команды, повторяемой из журнала. nil восстанавливает текущее время.
+func (pools *AllPools) SetClock(now func() time.Time) {
+	pools.commits.setNow(now)
+}
+
+// Now возвращает время по источнику времени пулов.
+func (pools *AllPools) Now() time.Time {
+	return pools.commits.current()
+}
+
+// Version одна версия значения ключа. Deleted отмечает удаление ключа в момент Time,
+// Previous хранит значение, которое эта версия заменила или удалила.
+type Version struct {
+	Time     time.Time
+	Value    interface{}
+	Previous interface{}
+	Deleted  bool
+}
+
+// History хранит упорядоченные по времени версии всех ключей коллекции.
+// Чтение истории не ждет изменений коллекции: блокировка истории
+// удерживается только на время добавления одной версии.
+type History struct {
+	mu       sync.RWMutex
+	versions map[string][]Version
+}
+
+func NewHistory() *History {
+	return &History{
+		versions: make(map[string][]Version),
+	}
+}
+
+// Record добавляет новую версию ключа.
+func (h *History) Record(key string, version Version) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.versions[key] = append(h.versions[key], version)
+}
+
+// latest возвращает время самой поздней версии истории.
+func (h *History) latest() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var latest time.Time
+	for _, versions := range h.versions {
+		if n := len(versions); n > 0 && versions[n-1].Time.After(latest) {
+			latest = versions[n-1].Time
+		}
+	}
+	return latest
+}
+
+// Versions возвращает все версии ключа в порядке их появления.
+func (h *History) Versions(key string) []Version {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]Version(nil), h.versions[key]...)
+}
+
+// ValueAt возвращает значение ключа, актуальное на момент t.
+func (h *History) ValueAt(key string, t time.Time) (interface{}, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.valueAt(key, t)
+}
+
+func (h *History) valueAt(key string, t time.Time) (interface{}, error) {
+	versions := h.versions[key]
+	// Индекс первой версии, появившейся позже t
+	i := sort.Search(len(versions), func(i int) bool {
+		return versions[i].Time.After(t)
+	})
+	if i == 0 || versions[i-1].Deleted {
+		return nil, ErrKeyNotFound
+	}
+	return versions[i-1].Value, nil
+}
+
+// SnapshotAt восстанавливает состояние всей коллекции на момент t.
+func (h *History) SnapshotAt(t time.Time) map[string]interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	snapshot := make(map[string]interface{})
+	for key := range h.versions {
+		if value, err := h.valueAt(key, t); err == nil {
+			snapshot[key] = value
+		}
+	}
+	return snapshot
+}
+
+// VersionedCollection коллекция, записывающая каждое изменение в историю.
+// Если задано описание полей, каждая вставляемая и изменяемая запись
+// проверяется по нему до записи. Вторичные индексы обновляются вместе с коллекцией.
+//
+// Коллекция безопасна для одновременного использования: изменения выполняются
+// под блокировкой записи, чтение текущего состояния — под блокировкой чтения,
+// а чтение на момент времени обращается только к истории (см. mvcc.go).
+type VersionedCollection struct {
+	mu sync.RWMutex
+	// generation увеличивается при каждом изменении коллекции
+	generation uint64
+	collection tree.Collection
+	history    *History
+	fields     *RecordSchema
+	indexes    map[string]*Index
+	commits    *commitClock // часы фиксации пулов, в которые добавлена коллекция
+}
+
+func NewVersionedCollection(collection tree.Collection) *VersionedCollection {
+	return &VersionedCollection{
+		collection: collection,
+		history:    NewHistory(),
+		indexes:    make(map[string]*Index),
+		commits:    newCommitClock(),
+	}
+}
+
+// setCommits переводит коллекцию на часы фиксации commits; новые изменения
+// получат время позже всех версий ее истории.
+func (vc *VersionedCollection) setCommits(commits *commitClock) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.commits = commits
+	commits.observe(vc.history.latest())
+}
+
+// RecordSchema возвращает описание полей записей или nil, если оно не задано.
+func (vc *VersionedCollection) RecordSchema() *RecordSchema {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.fields
+}
+
+// SetRecordSchema задает описание полей, по которому проверяются новые записи.
+func (vc *VersionedCollection) SetRecordSchema(fields *RecordSchema) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.fields = fields
+}
+
+// validate проверяет запись по описанию полей, если оно задано.
+func (vc *VersionedCollection) validate(value interface{}) (interface{}, error) {
+	if vc.fields == nil {
+		return value, nil
+	}
+	return vc.fields.Validate(value)
+}
+
+// History возвращает историю изменений коллекции.
+func (vc *VersionedCollection) History() *History {
+	return vc.history
+}
+
+func (vc *VersionedCollection) Insert(key string, value interface{}) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	// Время фиксации берется под блокировкой коллекции, поэтому версии каждой
+	// записи попадают в историю в порядке возрастания времени
+	t := vc.commits.begin()
+	defer vc.commits.end(t)
+	return vc.insertAt(key, value, t)
+}
+
+// insertAt вставляет запись, записывая в историю версию со временем t.
+// Вызывается под блокировкой vc.mu на запись.
+func (vc *VersionedCollection) insertAt(key string, value interface{}, t time.Time) error {
+	value, err := vc.validate(value)
+	if err != nil {
+		return err
+	}
+	if err := vc.collection.Insert(key, value); err != nil {
+		return err
+	}
+	vc.updateIndexes(key, nil, value, false, true)
+	vc.history.Record(key, Version{Time: t, Value: value})
+	vc.generation++
+	return nil
+}
+
+func (vc *VersionedCollection) Get(key string) (interface{}, error) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.collection.Get(key)
+}
+
+// GetAt возвращает значение ключа на момент t.
+func (vc *VersionedCollection) GetAt(key string, t time.Time) (interface{}, error) {
+	return vc.history.ValueAt(key, t)
+}
+
+// SnapshotAt возвращает все записи коллекции в том виде, в котором они были на момент t.
+func (vc *VersionedCollection) SnapshotAt(t time.Time) map[string]interface{} {
+	return vc.history.SnapshotAt(t)
+}
+
+func (vc *VersionedCollection) GetRange(minValue, maxValue string) ([]string, error) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.collection.GetRange(minValue, maxValue)
+}
+
+func (vc *VersionedCollection) GetRangeRecords(minValue, maxValue string) ([]tree.Record, error) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.collection.GetRangeRecords(minValue, maxValue)
+}
+
+func (vc *VersionedCollection) Update(key string, value interface{}) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	t := vc.commits.begin()
+	defer vc.commits.end(t)
+	return vc.updateAt(key, value, t)
+}
+
+// updateAt изменяет запись, записывая в историю версию со временем t.
+// Вызывается под блокировкой vc.mu на запись.
+func (vc *VersionedCollection) updateAt(key string, value interface{}, t time.Time) error {
+	value, err := vc.validate(value)
+	if err != nil {
+		return err
+	}
+	previous, err := vc.collection.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := vc.collection.Update(key, value); err != nil {
+		return err
+	}
+	vc.updateIndexes(key, previous, value, true, true)
+	vc.history.Record(key, Version{Time: t, Value: value, Previous: previous})
+	vc.generation++
+	return nil
+}
+
+func (vc *VersionedCollection) Remove(key string) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	t := vc.commits.begin()
+	defer vc.commits.end(t)
+	return vc.removeAt(key, t)
+}
+
+// removeAt удаляет запись, записывая в историю версию со временем t.
+// Вызывается под блокировкой vc.mu на запись.
+func (vc *VersionedCollection) removeAt(key string, t time.Time) error {
+	previous, err := vc.collection.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := vc.collection.Remove(key); err != nil {
+		return err
+	}
+	vc.updateIndexes(key, previous, nil, true, false)
+	vc.history.Record(key, Version{Time: t, Previous: previous, Deleted: true})
+	vc.generation++
+	return nil
+}
+
+// ForEach обходит записи под блокировкой чтения, поэтому fn не должна изменять коллекцию.
+func (vc *VersionedCollection) ForEach(fn func(key string, value interface{}) bool) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	vc.collection.ForEach(fn)
+}
+
+// Cursor возвращает курсор, который остается пригодным и при изменениях
+// коллекции: после изменения он продолжает обход с того же ключа.
+func (vc *VersionedCollection) Cursor() tree.Cursor {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return &versionedCursor{vc: vc, cursor: vc.collection.Cursor(), generation: vc.generation}
+}
@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"db/tree"
+)
+
+// newTestPools создает пулы с пулом p со схемой s и коллекциями с именами names.
+func newTestPools(t *testing.T, names ...string) *AllPools {
+	t.Helper()
+	pools := InitPool()
+	if err := pools.AddPool("p"); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pools.GetPool("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddSchema("s"); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := pool.GetSchema("s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := schema.AddCollection(name, tree.NewMapCollection()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return pools
+}
+
+func TestHistoryVersionsInTimeOrder(t *testing.T) {
+	pools := newTestPools(t, "c")
+	coll, err := pools.LookupCollection("p", "s", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := coll.Insert("k", int64(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 300; j++ {
+				if i%2 == 0 {
+					coll.Update("k", int64(i*1000+j))
+					continue
+				}
+				tx, err := pools.Begin("p")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				tx.Update("s", "c", "k", int64(i*1000+j))
+				tx.Commit()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	versions := coll.History().Versions("k")
+	for i := 1; i < len(versions); i++ {
+		if !versions[i-1].Time.Before(versions[i].Time) {
+			t.Fatalf("версия %d (%v) не позже версии %d (%v)", i, versions[i].Time, i-1, versions[i-1].Time)
+		}
+	}
+	// Последняя версия истории совпадает с текущим значением
+	current, err := coll.Get("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := versions[len(versions)-1]
+	if last.Value != current {
+		t.Fatalf("последняя версия %v, текущее значение %v", last.Value, current)
+	}
+	if value, err := coll.GetAt("k", last.Time); err != nil || value != current {
+		t.Fatalf("значение на момент последней версии %v (%v), ожидалось %v", value, err, current)
+	}
+}
+
+func TestClockIsPerPools(t *testing.T) {
+	replayed, live := newTestPools(t, "c"), newTestPools(t, "c")
+	past := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	replayed.SetClock(func() time.Time { return past })
+	defer replayed.SetClock(nil)
+
+	for _, pools := range []*AllPools{replayed, live} {
+		coll, _ := pools.LookupCollection("p", "s", "c")
+		if err := coll.Insert("k", int64(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	coll, _ := replayed.LookupCollection("p", "s", "c")
+	if versions := coll.History().Versions("k"); !versions[0].Time.Equal(past) {
+		t.Fatalf("версия получила время %v, ожидалось %v", versions[0].Time, past)
+	}
+	// Часы одних пулов не влияют на другие
+	coll, _ = live.LookupCollection("p", "s", "c")
+	if versions := coll.History().Versions("k"); !versions[0].Time.After(past) {
+		t.Fatalf("версия других пулов получила время %v", versions[0].Time)
+	}
+	if !live.Snapshot().Time().After(past) {
+		t.Fatalf("срез других пулов на %v", live.Snapshot().Time())
+	}
+}
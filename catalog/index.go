@@ -0,0 +1,283 @@
+package catalog
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"db/i18n"
+	"db/tree"
+)
+
+// Index вторичный упорядоченный индекс по полю структурированных записей.
+// Ключ индекса — закодированное значение поля, за которым следует первичный
+// ключ записи, поэтому записи с одинаковым значением поля не конфликтуют.
+// Записи, не являющиеся объектами или не содержащие поля, в индекс не попадают.
+type Index struct {
+	mu        sync.RWMutex
+	field     string
+	indexType string
+	tree      tree.Tree
+}
+
+// NewIndex создает пустой индекс по полю field на основе АВЛ- или B-дерева.
+func NewIndex(field, indexType string) (*Index, error) {
+	var index tree.Tree
+	switch indexType {
+	case "", "avl":
+		indexType = "avl"
+		index = tree.NewAVLTree()
+	case "btree":
+		index, _ = tree.NewBTree(tree.DefaultBTreeDegree)
+	default:
+		return nil, i18n.Errorf(i18n.UnknownIndexType, indexType)
+	}
+	return &Index{field: field, indexType: indexType, tree: index}, nil
+}
+
+// Field возвращает имя индексируемого поля.
+func (ix *Index) Field() string {
+	return ix.field
+}
+
+// Type возвращает тип дерева индекса.
+func (ix *Index) Type() string {
+	return ix.indexType
+}
+
+// add добавляет запись в индекс.
+func (ix *Index) add(primaryKey string, record interface{}) error {
+	value, ok := fieldValue(record, ix.field)
+	if !ok {
+		return nil
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.tree.Insert(indexKey(value, primaryKey), primaryKey)
+}
+
+// remove удаляет запись из индекса.
+func (ix *Index) remove(primaryKey string, record interface{}) error {
+	value, ok := fieldValue(record, ix.field)
+	if !ok {
+		return nil
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.tree.Remove(indexKey(value, primaryKey))
+}
+
+// Lookup возвращает первичные ключи записей, у которых поле равно value.
+func (ix *Index) Lookup(value interface{}) ([]string, error) {
+	return ix.Range(value, value)
+}
+
+// Range возвращает первичные ключи записей, у которых значение поля лежит
+// в диапазоне [minValue, maxValue], в порядке возрастания значения поля.
+func (ix *Index) Range(minValue, maxValue interface{}) ([]string, error) {
+	return ix.rangeKeys(encodeIndexValue(minValue)+indexSeparator, encodeIndexValue(maxValue)+indexUpperBound)
+}
+
+// rangeKeys возвращает первичные ключи записей, ключи индекса которых лежат
+// в диапазоне [lower, upper].
+func (ix *Index) rangeKeys(lower, upper string) ([]string, error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	records, err := ix.tree.GetRangeRecords(lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, record.Value.(string))
+	}
+	return keys, nil
+}
+
+// fieldValue возвращает значение поля записи-объекта.
+func fieldValue(record interface{}, field string) (interface{}, bool) {
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := object[field]
+	return value, ok
+}
+
+const (
+	// indexSeparator отделяет закодированное значение поля от первичного ключа;
+	// байт 0x00 внутри значений экранируется как 0x00 0xff
+	indexSeparator = "\x00\x01"
+	// indexUpperBound больше любого ключа индекса с тем же значением поля
+	indexUpperBound = "\x00\x02"
+)
+
+func indexKey(value interface{}, primaryKey string) string {
+	return encodeIndexValue(value) + indexSeparator + primaryKey
+}
+
+// encodeIndexValue кодирует значение поля в строку, порядок которой совпадает
+// с порядком значений: null < логические < числа < строки < объекты и массивы.
+// Целые и дробные числа сравниваются между собой по величине.
+func encodeIndexValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "0"
+	case bool:
+		if v {
+			return "11"
+		}
+		return "10"
+	case int64:
+		return "2" + encodeFloat(float64(v))
+	case int:
+		return "2" + encodeFloat(float64(v))
+	case float64:
+		return "2" + encodeFloat(v)
+	case string:
+		return "3" + escapeIndexString(v)
+	default:
+		return "4" + escapeIndexString(FormatValue(v))
+	}
+}
+
+// encodeFloat кодирует число так, что порядок строк совпадает с порядком чисел.
+func encodeFloat(f float64) string {
+	bits := math.Float64bits(f)
+	if f >= 0 {
+		bits |= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return fmt.Sprintf("%016x", bits)
+}
+
+func escapeIndexString(s string) string {
+	return strings.ReplaceAll(s, "\x00", "\x00\xff")
+}
+
+// compareIndexValues сравнивает значения полей в порядке индекса.
+func compareIndexValues(a, b interface{}) int {
+	return strings.Compare(encodeIndexValue(a), encodeIndexValue(b))
+}
+
+// CreateIndex строит индекс по полю и поддерживает его при изменениях коллекции.
+func (vc *VersionedCollection) CreateIndex(field, indexType string) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if _, exists := vc.indexes[field]; exists {
+		return i18n.Errorf(i18n.IndexExists, field)
+	}
+	index, err := NewIndex(field, indexType)
+	if err != nil {
+		return err
+	}
+	vc.collection.ForEach(func(key string, value interface{}) bool {
+		err = index.add(key, value)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	vc.indexes[field] = index
+	return nil
+}
+
+// Indexes возвращает индексы коллекции, упорядоченные по имени поля.
+func (vc *VersionedCollection) Indexes() []*Index {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.sortedIndexes()
+}
+
+// sortedIndexes возвращает индексы, упорядоченные по имени поля; вызывается
+// под блокировкой коллекции.
+func (vc *VersionedCollection) sortedIndexes() []*Index {
+	indexes := make([]*Index, 0, len(vc.indexes))
+	for _, index := range vc.indexes {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].field < indexes[j].field
+	})
+	return indexes
+}
+
+// index возвращает индекс по полю, если он существует.
+func (vc *VersionedCollection) index(field string) (*Index, bool) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	index, exists := vc.indexes[field]
+	return index, exists
+}
+
+// FindByField возвращает записи, у которых поле равно value.
+func (vc *VersionedCollection) FindByField(field string, value interface{}) ([]tree.Record, error) {
+	return vc.FindRangeByField(field, value, value)
+}
+
+// FindRangeByField возвращает записи, у которых значение поля лежит в диапазоне
+// [minValue, maxValue], упорядоченные по значению поля и первичному ключу.
+// При наличии индекса по полю используется он, иначе просматривается срез
+// коллекции на текущий момент, не блокируя писателей.
+func (vc *VersionedCollection) FindRangeByField(field string, minValue, maxValue interface{}) ([]tree.Record, error) {
+	vc.mu.RLock()
+	index, exists := vc.indexes[field]
+	commits := vc.commits
+	vc.mu.RUnlock()
+	if exists {
+		return vc.indexedRecords(index, encodeIndexValue(minValue)+indexSeparator, encodeIndexValue(maxValue)+indexUpperBound)
+	}
+
+	var records []tree.Record
+	snapshot := &snapshotCollection{vc: vc, time: commits.stable()}
+	snapshot.ForEach(func(key string, value interface{}) bool {
+		if fv, ok := fieldValue(value, field); ok &&
+			compareIndexValues(fv, minValue) >= 0 && compareIndexValues(fv, maxValue) <= 0 {
+			records = append(records, tree.Record{Key: key, Value: value})
+		}
+		return true
+	})
+	sort.SliceStable(records, func(i, j int) bool {
+		a, _ := fieldValue(records[i].Value, field)
+		b, _ := fieldValue(records[j].Value, field)
+		return compareIndexValues(a, b) < 0
+	})
+	return records, nil
+}
+
+// indexedRecords возвращает записи, ключи индекса которых лежат в диапазоне
+// [lower, upper], в порядке индекса. Индекс и записи читаются под одной
+// блокировкой, поэтому результат соответствует одному состоянию коллекции.
+func (vc *VersionedCollection) indexedRecords(index *Index, lower, upper string) ([]tree.Record, error) {
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	keys, err := index.rangeKeys(lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]tree.Record, 0, len(keys))
+	for _, key := range keys {
+		value, err := vc.collection.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, tree.Record{Key: key, Value: value})
+	}
+	return records, nil
+}
+
+// updateIndexes отражает в индексах замену значения previous на value;
+// hadPrevious и hasValue показывают, существовала ли запись до и после изменения.
+func (vc *VersionedCollection) updateIndexes(key string, previous, value interface{}, hadPrevious, hasValue bool) {
+	for _, index := range vc.indexes {
+		if hadPrevious {
+			index.remove(key, previous)
+		}
+		if hasValue {
+			index.add(key, value)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package catalog
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// orderedValues значения полей в порядке индекса: null, логические, числа
+// по величине независимо от типа, строки, объекты и массивы.
+var orderedValues = []interface{}{
+	nil,
+	false,
+	true,
+	math.Inf(-1),
+	-1e300,
+	int64(-1 << 53),
+	int64(-1000),
+	-2.5,
+	int64(-2),
+	-1.0000001,
+	int64(-1),
+	-1e-300,
+	0.0,
+	1e-300,
+	0.5,
+	int64(1),
+	1.5,
+	int64(2),
+	int64(10),
+	100.25,
+	int64(1 << 53),
+	1e300,
+	math.Inf(1),
+	"",
+	"\x00",
+	"\x00\x00",
+	"\x00a",
+	"\x01",
+	"-1",
+	"10",
+	"2",
+	"A",
+	"a",
+	"a\x00",
+	"a\x00b",
+	"ab",
+	"b",
+	"я",
+	"\U0001F600",
+}
+
+func TestIndexValueOrder(t *testing.T) {
+	shuffled := append([]interface{}(nil), orderedValues...)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	sort.SliceStable(shuffled, func(i, j int) bool {
+		return compareIndexValues(shuffled[i], shuffled[j]) < 0
+	})
+	if !reflect.DeepEqual(shuffled, orderedValues) {
+		t.Fatalf("порядок %#v, ожидался %#v", shuffled, orderedValues)
+	}
+	for i := 1; i < len(orderedValues); i++ {
+		if compareIndexValues(orderedValues[i-1], orderedValues[i]) >= 0 {
+			t.Errorf("%#v не меньше %#v", orderedValues[i-1], orderedValues[i])
+		}
+	}
+
+	// Целые и дробные числа равной величины равны
+	for _, pair := range [][2]interface{}{
+		{int64(1), 1.0}, {int64(0), 0.0}, {0.0, math.Copysign(0, -1)}, {int64(-7), -7.0}, {int(3), int64(3)},
+	} {
+		if cmp := compareIndexValues(pair[0], pair[1]); cmp != 0 {
+			t.Errorf("%#v и %#v: сравнение %d, ожидалось 0", pair[0], pair[1], cmp)
+		}
+	}
+}
+
+func TestIndexRangeOverMixedValues(t *testing.T) {
+	for _, indexType := range []string{"avl", "btree"} {
+		t.Run(indexType, func(t *testing.T) {
+			index, err := NewIndex("v", indexType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Записи вставляются в обратном порядке, а ключи не совпадают с порядком значений
+			for i := len(orderedValues) - 1; i >= 0; i-- {
+				record := map[string]interface{}{"v": orderedValues[i]}
+				if err := index.add(fmt.Sprintf("k%02d", len(orderedValues)-i), record); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Одинаковые значения упорядочены по первичному ключу
+			index.add("k00", map[string]interface{}{"v": 1.0})
+			index.add("k99", map[string]interface{}{"v": int64(1)})
+			index.add("skip", map[string]interface{}{"other": 1})
+
+			keys, err := index.Range(nil, "\U0001F600")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []interface{}
+			for _, key := range keys {
+				switch key {
+				case "k00", "k99":
+					got = append(got, key)
+				default:
+					var n int
+					fmt.Sscanf(key, "k%d", &n)
+					got = append(got, orderedValues[len(orderedValues)-n])
+				}
+			}
+			var want []interface{}
+			for _, value := range orderedValues {
+				if value == int64(1) {
+					want = append(want, "k00", value, "k99")
+					continue
+				}
+				want = append(want, value)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("порядок %#v, ожидался %#v", got, want)
+			}
+
+			// Диапазоны внутри вида значений и на границах видов
+			for _, test := range []struct {
+				min, max interface{}
+				count    int
+			}{
+				{int64(-2), int64(2), 12},
+				{-2.0, 2.0, 12},
+				{false, true, 2},
+				{"a", "b", 5},
+				{true, "", 24},
+				{"\x00", "\x00", 1},
+				{int64(3), int64(9), 0},
+			} {
+				keys, err := index.Range(test.min, test.max)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(keys) != test.count {
+					t.Errorf("[%#v, %#v]: %d записей %v, ожидалось %d", test.min, test.max, len(keys), keys, test.count)
+				}
+			}
+		})
+	}
+}
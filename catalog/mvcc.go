@@ -0,0 +1,348 @@
+package catalog
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"db/i18n"
+	"db/tree"
+)
+
+// Многоверсионное управление конкурентным доступом.
+//
+// Каждое изменение записи получает время фиксации и сохраняется в истории
+// коллекции как новая версия, поэтому читатель, знающий момент своего среза,
+// восстанавливает состояние на этот момент по истории и не ждет писателей.
+// Время фиксации выдает commitClock: оно строго возрастает, а срез берется
+// на момент последнего начатого изменения и ждет завершения всех изменений
+// до этого момента, так что читатель не видит изменение (или транзакцию)
+// частично и видит все изменения, завершенные до создания среза.
+
+// commitClock выдает времена фиксации и следит за незавершенными изменениями.
+// Часы принадлежат AllPools и общие для всех его коллекций; коллекция, еще
+// не добавленная в пулы, пользуется собственными часами.
+type commitClock struct {
+	mu     sync.Mutex
+	ended  *sync.Cond       // сигнализирует о завершении изменения
+	now    func() time.Time // источник времени; при повторе журнала — время команды
+	last   time.Time
+	active map[time.Time]int
+}
+
+func newCommitClock() *commitClock {
+	c := &commitClock{
+		now:    time.Now,
+		active: make(map[time.Time]int),
+	}
+	c.ended = sync.NewCond(&c.mu)
+	return c
+}
+
+// begin выдает время фиксации нового изменения. Пока изменение не завершено
+// вызовом end, срезы на момент не раньше него ждут его завершения.
+func (c *commitClock) begin() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.now()
+	if !t.After(c.last) {
+		t = c.last.Add(time.Nanosecond)
+	}
+	c.last = t
+	c.active[t]++
+	return t
+}
+
+// end отмечает изменение со временем t завершенным.
+func (c *commitClock) end(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active[t]--; c.active[t] <= 0 {
+		delete(c.active, t)
+	}
+	c.ended.Broadcast()
+}
+
+// observe учитывает время уже существующей версии, например восстановленной
+// из файла данных, чтобы новые изменения получали более позднее время.
+func (c *commitClock) observe(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.last) {
+		c.last = t
+	}
+}
+
+// setNow задает источник времени; nil восстанавливает текущее время.
+func (c *commitClock) setNow(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now == nil {
+		now = time.Now
+	}
+	c.now = now
+}
+
+// current возвращает время по источнику времени часов.
+func (c *commitClock) current() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+// stable возвращает время последнего начатого изменения, дождавшись завершения
+// всех изменений до него. Изменения, начатые во время ожидания, получают более
+// позднее время и не задерживают его. Нельзя вызывать, удерживая блокировку
+// коллекции: изменение, которого ждет срез, может ждать этой блокировки.
+func (c *commitClock) stable() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stable := c.last
+	for c.activeUntil(stable) {
+		c.ended.Wait()
+	}
+	return stable
+}
+
+// activeUntil сообщает, есть ли незавершенные изменения со временем не позже t.
+// Вызывается под блокировкой c.mu.
+func (c *commitClock) activeUntil(t time.Time) bool {
+	for active := range c.active {
+		if !active.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot согласованный срез всех коллекций на один момент времени.
+// Чтение из среза не блокирует писателей и не видит изменений,
+// зафиксированных после его создания.
+type Snapshot struct {
+	pools *AllPools
+	time  time.Time
+}
+
+// Snapshot создает срез, в который входят все изменения, завершенные до его создания.
+func (pools *AllPools) Snapshot() *Snapshot {
+	return &Snapshot{pools: pools, time: pools.commits.stable()}
+}
+
+// Time возвращает момент, на который сделан срез.
+func (s *Snapshot) Time() time.Time {
+	return s.time
+}
+
+// Get возвращает значение записи на момент среза.
+func (s *Snapshot) Get(poolName, schemaName, collectionName, key string) (interface{}, error) {
+	collection, err := s.pools.LookupCollection(poolName, schemaName, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	return collection.GetAt(key, s.time)
+}
+
+// Records возвращает записи коллекции на момент среза, упорядоченные по ключу.
+func (s *Snapshot) Records(poolName, schemaName, collectionName string) ([]tree.Record, error) {
+	collection, err := s.pools.LookupCollection(poolName, schemaName, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	return collection.RecordsAt(s.time), nil
+}
+
+// Cursor возвращает упорядоченный курсор по записям коллекции на момент среза.
+func (s *Snapshot) Cursor(poolName, schemaName, collectionName string) (tree.Cursor, error) {
+	collection, err := s.Collection(poolName, schemaName, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Cursor(), nil
+}
+
+// Collection возвращает коллекцию в том виде, в котором она была на момент среза.
+func (s *Snapshot) Collection(poolName, schemaName, collectionName string) (tree.Collection, error) {
+	collection, err := s.pools.LookupCollection(poolName, schemaName, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	return s.View(collection), nil
+}
+
+// View возвращает коллекцию collection на момент среза. Коллекция доступна только
+// для чтения: ее записи восстанавливаются по истории при первом обходе, поэтому
+// чтение не блокирует писателей коллекции.
+func (s *Snapshot) View(collection *VersionedCollection) tree.Collection {
+	return &snapshotCollection{vc: collection, time: s.time}
+}
+
+// snapshotCollection коллекция на момент времени, доступная только для чтения.
+type snapshotCollection struct {
+	vc      *VersionedCollection
+	time    time.Time
+	records *tree.MapCollection // записи на момент time; nil до первого обхода
+}
+
+func (c *snapshotCollection) load() *tree.MapCollection {
+	if c.records == nil {
+		c.records = tree.NewMapCollection()
+		for key, value := range c.vc.history.SnapshotAt(c.time) {
+			c.records.Insert(key, value)
+		}
+	}
+	return c.records
+}
+
+func (c *snapshotCollection) Insert(key string, value interface{}) error {
+	return i18n.Errorf(i18n.SnapshotReadOnly)
+}
+
+func (c *snapshotCollection) Update(key string, value interface{}) error {
+	return i18n.Errorf(i18n.SnapshotReadOnly)
+}
+
+func (c *snapshotCollection) Remove(key string) error {
+	return i18n.Errorf(i18n.SnapshotReadOnly)
+}
+
+func (c *snapshotCollection) Get(key string) (interface{}, error) {
+	return c.vc.GetAt(key, c.time)
+}
+
+func (c *snapshotCollection) GetRange(minValue, maxValue string) ([]string, error) {
+	return c.load().GetRange(minValue, maxValue)
+}
+
+func (c *snapshotCollection) GetRangeRecords(minValue, maxValue string) ([]tree.Record, error) {
+	return c.load().GetRangeRecords(minValue, maxValue)
+}
+
+func (c *snapshotCollection) ForEach(fn func(key string, value interface{}) bool) {
+	c.load().ForEach(fn)
+}
+
+func (c *snapshotCollection) Cursor() tree.Cursor {
+	return c.load().Cursor()
+}
+
+// RecordsAt возвращает записи коллекции на момент t, упорядоченные по ключу.
+func (vc *VersionedCollection) RecordsAt(t time.Time) []tree.Record {
+	snapshot := vc.history.SnapshotAt(t)
+	records := make([]tree.Record, 0, len(snapshot))
+	for key, value := range snapshot {
+		records = append(records, tree.Record{Key: key, Value: value})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+	return records
+}
+
+// versionedCursor курсор по текущему состоянию коллекции. Каждый шаг выполняется
+// под блокировкой чтения; если коллекция изменилась с предыдущего шага,
+// курсор заново находит свое место по ключу, поэтому обход продолжается
+// с учетом изменений, а не по устаревшим узлам дерева.
+type versionedCursor struct {
+	vc         *VersionedCollection
+	cursor     tree.Cursor
+	generation uint64
+	key        string
+	valid      bool
+	// onKey показывает, что нижележащий курсор стоит на записи с ключом key
+	onKey bool
+}
+
+func (c *versionedCursor) position(ok bool) bool {
+	c.generation = c.vc.generation
+	c.valid = ok
+	c.onKey = ok
+	if ok {
+		c.key = c.cursor.Key()
+	}
+	return ok
+}
+
+// resync возвращает true, если курсор указывает на запись с ключом c.key;
+// иначе он установлен на первую запись с большим ключом.
+func (c *versionedCursor) resync() bool {
+	if c.generation != c.vc.generation {
+		c.cursor.Close()
+		c.cursor = c.vc.collection.Cursor()
+		c.generation = c.vc.generation
+		c.onKey = c.cursor.Seek(c.key) && c.cursor.Key() == c.key
+	}
+	return c.onKey
+}
+
+func (c *versionedCursor) Seek(key string) bool {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	c.resync()
+	return c.position(c.cursor.Seek(key))
+}
+
+func (c *versionedCursor) First() bool {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	c.resync()
+	return c.position(c.cursor.First())
+}
+
+func (c *versionedCursor) Last() bool {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	c.resync()
+	return c.position(c.cursor.Last())
+}
+
+func (c *versionedCursor) Next() bool {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	if !c.valid {
+		return false
+	}
+	if !c.resync() {
+		// Текущая запись удалена, курсор уже стоит на следующей
+		return c.position(c.cursor.Valid())
+	}
+	return c.position(c.cursor.Next())
+}
+
+func (c *versionedCursor) Prev() bool {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	if !c.valid {
+		return false
+	}
+	if !c.resync() {
+		// Текущая запись удалена: предыдущая стоит перед найденной следующей
+		if !c.cursor.Valid() {
+			return c.position(c.cursor.Last())
+		}
+	}
+	return c.position(c.cursor.Prev())
+}
+
+func (c *versionedCursor) Valid() bool {
+	return c.valid
+}
+
+func (c *versionedCursor) Key() string {
+	return c.key
+}
+
+func (c *versionedCursor) Value() interface{} {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	if !c.resync() {
+		return nil
+	}
+	return c.cursor.Value()
+}
+
+func (c *versionedCursor) Close() {
+	c.vc.mu.RLock()
+	defer c.vc.mu.RUnlock()
+	c.cursor.Close()
+	c.valid = false
+}
@@ -0,0 +1,76 @@
+package catalog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSnapshotScanDoesNotBlockWriters(t *testing.T) {
+	pools := newTestPools(t, "c")
+	coll, _ := pools.LookupCollection("p", "s", "c")
+	for i := 0; i < 5; i++ {
+		if err := coll.Insert(fmt.Sprintf("k%d", i), int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	view := pools.Snapshot().View(coll)
+	var keys []string
+	view.ForEach(func(key string, value interface{}) bool {
+		// Изменение коллекции во время обхода среза не ждет его окончания
+		if err := coll.Update(key, int64(-1)); err != nil {
+			t.Fatal(err)
+		}
+		if err := coll.Insert(key+"-new", int64(0)); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if want := []string{"k0", "k1", "k2", "k3", "k4"}; fmt.Sprint(keys) != fmt.Sprint(want) {
+		t.Fatalf("срез содержит %v, ожидалось %v", keys, want)
+	}
+	if value, err := view.Get("k2"); err != nil || value != int64(2) {
+		t.Fatalf("k2 в срезе %v (%v)", value, err)
+	}
+	if err := view.Insert("x", int64(1)); err == nil {
+		t.Fatal("срез принял изменение")
+	}
+	if records, _ := pools.Snapshot().View(coll).GetRangeRecords("", "\xff"); len(records) != 10 {
+		t.Fatalf("новый срез содержит %d записей, ожидалось 10", len(records))
+	}
+}
+
+func TestSnapshotWaitsForStartedChanges(t *testing.T) {
+	pools := newTestPools(t, "a", "b")
+	a, _ := pools.LookupCollection("p", "s", "a")
+	b, _ := pools.LookupCollection("p", "s", "b")
+
+	// Изменение, начатое раньше, еще не завершено, а более позднее уже завершено
+	started := pools.commits.begin()
+	if err := b.Insert("k", int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	taken := make(chan *Snapshot)
+	go func() {
+		taken <- pools.Snapshot()
+	}()
+	select {
+	case <-taken:
+		t.Fatal("срез создан до завершения начатого изменения")
+	case <-time.After(50 * time.Millisecond):
+	}
+	a.mu.Lock()
+	a.insertAt("k", int64(2), started)
+	a.mu.Unlock()
+	pools.commits.end(started)
+
+	// Срез видит оба изменения, в том числе завершенное до его создания
+	snapshot := <-taken
+	for _, name := range []string{"a", "b"} {
+		if _, err := snapshot.Get("p", "s", name, "k"); err != nil {
+			t.Fatalf("запись k коллекции %s не видна в срезе: %v", name, err)
+		}
+	}
+}
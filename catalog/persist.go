@@ -0,0 +1,102 @@
+package catalog
+
+import (
+	"time"
+
+	"db/i18n"
+)
+
+// Обработчик времени
+type Handler interface {
+	SetNext(handler Handler)
+	HandleRequest(request string) (interface{}, error)
+}
+
+type TimeHandler struct {
+	next           Handler
+	pool           *AllPools
+	poolName       string
+	schemaName     string
+	collectionName string
+	time           time.Time
+}
+
+func (th *TimeHandler) SetNext(handler Handler) {
+	th.next = handler
+}
+
+// HandleRequest возвращает значение ключа request на момент th.time.
+// Если время не указано, запрос передается следующему обработчику.
+func (th *TimeHandler) HandleRequest(request string) (interface{}, error) {
+	if th.time.IsZero() {
+		if th.next != nil {
+			return th.next.HandleRequest(request)
+		}
+		return nil, i18n.Errorf(i18n.TimeNotSpecified)
+	}
+	pool, err := th.pool.GetPool(th.poolName)
+	if err != nil {
+		return nil, err
+	}
+	return th.getDataAtTime(pool, request, th.time)
+}
+
+// Snapshot возвращает состояние всей коллекции на момент th.time.
+func (th *TimeHandler) Snapshot() (map[string]interface{}, error) {
+	if th.time.IsZero() {
+		return nil, i18n.Errorf(i18n.TimeNotSpecified)
+	}
+	pool, err := th.pool.GetPool(th.poolName)
+	if err != nil {
+		return nil, err
+	}
+	return th.getSnapshotAtTime(pool, th.time)
+}
+
+func (th *TimeHandler) getDataAtTime(pool *Pool, key string, t time.Time) (interface{}, error) {
+	schema, err := pool.GetSchema(th.schemaName)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := schema.GetVersionedCollection(th.collectionName)
+	if err != nil {
+		return nil, err
+	}
+	return collection.GetAt(key, t)
+}
+
+func (th *TimeHandler) getSnapshotAtTime(pool *Pool, t time.Time) (map[string]interface{}, error) {
+	schema, err := pool.GetSchema(th.schemaName)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := schema.GetVersionedCollection(th.collectionName)
+	if err != nil {
+		return nil, err
+	}
+	return collection.SnapshotAt(t), nil
+}
+
+// GetRecordAt возвращает значение записи в том виде, в котором оно было на момент t.
+func (pools *AllPools) GetRecordAt(poolName, schemaName, collectionName, key string, t time.Time) (interface{}, error) {
+	timeHandler := &TimeHandler{
+		pool:           pools,
+		poolName:       poolName,
+		schemaName:     schemaName,
+		collectionName: collectionName,
+		time:           t,
+	}
+	return timeHandler.HandleRequest(key)
+}
+
+// GetCollection возвращает все записи коллекции в том виде, в котором они были на момент t.
+func (pools *AllPools) GetCollection(poolName, schemaName, collectionName string, t time.Time) (map[string]interface{}, error) {
+	timeHandler := &TimeHandler{
+		pool:           pools,
+		poolName:       poolName,
+		schemaName:     schemaName,
+		collectionName: collectionName,
+		time:           t,
+	}
+	return timeHandler.Snapshot()
+}
@@ -0,0 +1,740 @@
+package catalog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"db/i18n"
+	"db/tree"
+)
+
+// Язык запросов:
+//
+//	select <поля> from <пул>.<схема>.<коллекция>
+//	    [where <условие>] [order by <поле> [asc|desc], ...] [limit <n> [offset <m>]]
+//
+// Поля перечисляются через запятую или задаются как *. Вложенные поля
+// указываются через точку (address.city). Псевдополе _key означает первичный
+// ключ записи, _value — значение записи целиком.
+//
+// Условия: <поле> =, !=, <>, <, <=, >, >= <литерал>; <поле> [not] like '<шаблон>'
+// (% — любая последовательность символов, _ — один символ); <поле> is [not] null;
+// объединение через and, or, not и скобки. Литералы: числа, строки в одинарных
+// или двойных кавычках, true, false, null. Сравнение на больше/меньше истинно
+// только для значений одного вида: чисел с числами, строк со строками.
+//
+// Если условие содержит ограничения на _key, записи читаются через GetRange
+// коллекции; если ограничено индексированное поле — через вторичный индекс;
+// иначе коллекция просматривается целиком. Запрос к пулам читает коллекцию
+// на момент среза (см. mvcc.go) и не блокирует писателей; чтение по индексу
+// затрагивает только подходящие записи и идет по текущему состоянию коллекции
+// под одной блокировкой чтения.
+
+// Query разобранный запрос.
+type Query struct {
+	fields     []string // nil означает все поля
+	pool       string
+	schema     string
+	collection string
+	where      queryCondition
+	orderBy    []orderField
+	limit      int // -1 — без ограничения
+	offset     int
+}
+
+type orderField struct {
+	field      string
+	descending bool
+}
+
+// queryCondition условие отбора записей.
+type queryCondition interface {
+	eval(key string, value interface{}) bool
+}
+
+type comparison struct {
+	field   string
+	op      string
+	literal interface{}
+}
+
+type likeCondition struct {
+	field   string
+	pattern string
+	negate  bool
+}
+
+type nullCondition struct {
+	field  string
+	negate bool
+}
+
+type andCondition struct {
+	left, right queryCondition
+}
+
+type orCondition struct {
+	left, right queryCondition
+}
+
+type notCondition struct {
+	condition queryCondition
+}
+
+func (c *comparison) eval(key string, value interface{}) bool {
+	fv, _ := lookupField(key, value, c.field)
+	return compareValues(fv, c.op, c.literal)
+}
+
+func (c *likeCondition) eval(key string, value interface{}) bool {
+	fv, _ := lookupField(key, value, c.field)
+	s, ok := fv.(string)
+	if !ok {
+		return false
+	}
+	return matchLike([]rune(s), []rune(c.pattern)) != c.negate
+}
+
+func (c *nullCondition) eval(key string, value interface{}) bool {
+	fv, _ := lookupField(key, value, c.field)
+	return (fv == nil) != c.negate
+}
+
+func (c *andCondition) eval(key string, value interface{}) bool {
+	return c.left.eval(key, value) && c.right.eval(key, value)
+}
+
+func (c *orCondition) eval(key string, value interface{}) bool {
+	return c.left.eval(key, value) || c.right.eval(key, value)
+}
+
+func (c *notCondition) eval(key string, value interface{}) bool {
+	return !c.condition.eval(key, value)
+}
+
+// lookupField возвращает значение поля записи; поддерживаются псевдополя
+// _key и _value и вложенные поля через точку.
+func lookupField(key string, value interface{}, field string) (interface{}, bool) {
+	switch field {
+	case "_key":
+		return key, true
+	case "_value":
+		return value, true
+	}
+	current := value
+	for _, part := range strings.Split(field, ".") {
+		fv, ok := fieldValue(current, part)
+		if !ok {
+			return nil, false
+		}
+		current = fv
+	}
+	return current, true
+}
+
+// compareValues сравнивает значение поля с литералом.
+func compareValues(value interface{}, op string, literal interface{}) bool {
+	switch op {
+	case "=":
+		return compareIndexValues(value, literal) == 0
+	case "!=":
+		return compareIndexValues(value, literal) != 0
+	}
+	var cmp int
+	switch {
+	case isNumber(value) && isNumber(literal):
+		cmp = compareIndexValues(value, literal)
+	case isString(value) && isString(literal):
+		cmp = strings.Compare(value.(string), literal.(string))
+	default:
+		return false
+	}
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int, int64, float64:
+		return true
+	}
+	return false
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+// matchLike сопоставляет строку с шаблоном like.
+func matchLike(s, pattern []rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '%':
+			for i := 0; i <= len(s); i++ {
+				if matchLike(s[i:], pattern[1:]) {
+					return true
+				}
+			}
+			return false
+		case '_':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		s, pattern = s[1:], pattern[1:]
+	}
+	return len(s) == 0
+}
+
+// Query разбирает и выполняет запрос к коллекции пула.
+func (pools *AllPools) Query(text string) ([]tree.Record, error) {
+	query, err := ParseQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := pools.LookupCollection(query.pool, query.schema, query.collection)
+	if err != nil {
+		return nil, err
+	}
+	return query.Execute(pools.Snapshot().View(collection))
+}
+
+// Execute выполняет запрос над коллекцией. Для *VersionedCollection и ее среза
+// используются вторичные индексы коллекции.
+func (q *Query) Execute(collection tree.Collection) ([]tree.Record, error) {
+	var records []tree.Record
+	// Без сортировки достаточно первых offset+limit подходящих записей
+	enough := func() bool {
+		return len(q.orderBy) == 0 && q.limit >= 0 && len(records) >= q.offset+q.limit
+	}
+	accept := func(key string, value interface{}) bool {
+		if q.where == nil || q.where.eval(key, value) {
+			records = append(records, tree.Record{Key: key, Value: value})
+		}
+		return !enough()
+	}
+
+	conjuncts := splitConjuncts(q.where)
+	if lower, upper, ok := keyBounds(conjuncts); ok {
+		if err := scanKeyRange(collection, lower, upper, accept); err != nil {
+			return nil, err
+		}
+	} else if versioned, index, lower, upper, ok := indexBounds(collection, conjuncts); ok {
+		indexed, err := versioned.indexedRecords(index, lower, upper)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range indexed {
+			if !accept(record.Key, record.Value) {
+				break
+			}
+		}
+	} else {
+		collection.ForEach(accept)
+	}
+
+	if len(q.orderBy) > 0 {
+		sort.SliceStable(records, func(i, j int) bool {
+			for _, order := range q.orderBy {
+				a, _ := lookupField(records[i].Key, records[i].Value, order.field)
+				b, _ := lookupField(records[j].Key, records[j].Value, order.field)
+				cmp := compareIndexValues(a, b)
+				if cmp == 0 {
+					continue
+				}
+				return (cmp < 0) != order.descending
+			}
+			return false
+		})
+	}
+	if q.offset >= len(records) {
+		records = nil
+	} else {
+		records = records[q.offset:]
+	}
+	if q.limit >= 0 && len(records) > q.limit {
+		records = records[:q.limit]
+	}
+	if q.fields != nil {
+		for i, record := range records {
+			records[i].Value = q.project(record.Key, record.Value)
+		}
+	}
+	return records, nil
+}
+
+// project оставляет в записи только выбранные поля.
+func (q *Query) project(key string, value interface{}) interface{} {
+	projected := make(map[string]interface{}, len(q.fields))
+	for _, field := range q.fields {
+		if fv, ok := lookupField(key, value, field); ok {
+			projected[field] = fv
+		}
+	}
+	return projected
+}
+
+// splitConjuncts раскладывает условие на части, соединенные через and.
+func splitConjuncts(condition queryCondition) []queryCondition {
+	switch c := condition.(type) {
+	case nil:
+		return nil
+	case *andCondition:
+		return append(splitConjuncts(c.left), splitConjuncts(c.right)...)
+	default:
+		return []queryCondition{c}
+	}
+}
+
+// keyBounds возвращает ограничения на первичный ключ; пустая граница означает
+// ее отсутствие. Строгость неравенств проверяется при фильтрации.
+func keyBounds(conjuncts []queryCondition) (lower, upper *string, ok bool) {
+	for _, condition := range conjuncts {
+		c, isComparison := condition.(*comparison)
+		if !isComparison || c.field != "_key" {
+			continue
+		}
+		key, isKey := c.literal.(string)
+		if !isKey {
+			continue
+		}
+		switch c.op {
+		case "=":
+			lower, upper = maxBound(lower, key), minBound(upper, key)
+		case ">", ">=":
+			lower = maxBound(lower, key)
+		case "<", "<=":
+			upper = minBound(upper, key)
+		default:
+			continue
+		}
+		ok = true
+	}
+	return lower, upper, ok
+}
+
+func maxBound(bound *string, key string) *string {
+	if bound == nil || key > *bound {
+		return &key
+	}
+	return bound
+}
+
+func minBound(bound *string, key string) *string {
+	if bound == nil || key < *bound {
+		return &key
+	}
+	return bound
+}
+
+// scanKeyRange передает fn записи коллекции с ключами в заданных границах
+// в порядке возрастания ключей.
+func scanKeyRange(collection tree.Collection, lower, upper *string, fn func(key string, value interface{}) bool) error {
+	if lower != nil && upper != nil {
+		records, err := collection.GetRangeRecords(*lower, *upper)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if !fn(record.Key, record.Value) {
+				break
+			}
+		}
+		return nil
+	}
+	cursor := collection.Cursor()
+	defer cursor.Close()
+	valid := cursor.First()
+	if lower != nil {
+		valid = cursor.Seek(*lower)
+	}
+	for ; valid; valid = cursor.Next() {
+		if upper != nil && cursor.Key() > *upper {
+			break
+		}
+		if !fn(cursor.Key(), cursor.Value()) {
+			break
+		}
+	}
+	return nil
+}
+
+// indexBounds ищет условие на индексированное поле и возвращает коллекцию
+// с индексом, индекс и закодированные границы диапазона для него.
+func indexBounds(collection tree.Collection, conjuncts []queryCondition) (*VersionedCollection, *Index, string, string, bool) {
+	var versioned *VersionedCollection
+	switch c := collection.(type) {
+	case *VersionedCollection:
+		versioned = c
+	case *snapshotCollection:
+		versioned = c.vc
+	default:
+		return nil, nil, "", "", false
+	}
+	for _, condition := range conjuncts {
+		c, isComparison := condition.(*comparison)
+		// Записи без поля равны null при проверке условия, но не попадают в индекс.
+		// Псевдополя и вложенные поля условие ищет иначе, чем индекс
+		if !isComparison || c.literal == nil || c.field == "_key" || c.field == "_value" || strings.Contains(c.field, ".") {
+			continue
+		}
+		index, exists := versioned.index(c.field)
+		if !exists {
+			continue
+		}
+		encoded := encodeIndexValue(c.literal)
+		// Значения одного вида закодированы с общим первым символом
+		classLower := encoded[:1]
+		classUpper := string(encoded[0] + 1)
+		switch c.op {
+		case "=":
+			return versioned, index, encoded + indexSeparator, encoded + indexUpperBound, true
+		case ">", ">=":
+			return versioned, index, encoded + indexSeparator, classUpper, true
+		case "<", "<=":
+			return versioned, index, classLower, encoded + indexUpperBound, true
+		}
+	}
+	return nil, nil, "", "", false
+}
+
+// ParseQuery разбирает текст запроса.
+func ParseQuery(text string) (*Query, error) {
+	tokens, err := tokenizeQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	query, err := p.parseSelect()
+	if err != nil {
+		return nil, i18n.Wrap(err, i18n.QueryError, err)
+	}
+	return query, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeQuery(text string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				value.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, i18n.Errorf(i18n.QueryUnclosedQuote)
+			}
+			tokens = append(tokens, queryToken{tokenString, value.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || strings.ContainsRune(".eE", runes[j]) ||
+				((runes[j] == '+' || runes[j] == '-') && (runes[j-1] == 'e' || runes[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, queryToken{tokenNumber, string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || strings.ContainsRune("_-.", runes[j])) {
+				j++
+			}
+			tokens = append(tokens, queryToken{tokenIdent, string(runes[i:j])})
+			i = j
+		case strings.ContainsRune("<>!=", r):
+			j := i + 1
+			if j < len(runes) && (runes[j] == '=' || (r == '<' && runes[j] == '>')) {
+				j++
+			}
+			tokens = append(tokens, queryToken{tokenSymbol, string(runes[i:j])})
+			i = j
+		case strings.ContainsRune(",()*", r):
+			tokens = append(tokens, queryToken{tokenSymbol, string(r)})
+			i++
+		default:
+			return nil, i18n.Errorf(i18n.QueryInvalidChar, r)
+		}
+	}
+	return append(tokens, queryToken{kind: tokenEOF}), nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// keyword проверяет, является ли следующий токен ключевым словом, и пропускает его.
+func (p *queryParser) keyword(word string) bool {
+	token := p.peek()
+	if token.kind == tokenIdent && strings.EqualFold(token.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expectKeyword(word string) error {
+	if !p.keyword(word) {
+		return i18n.Errorf(i18n.QueryExpected, word, p.peek().text)
+	}
+	return nil
+}
+
+func (p *queryParser) symbol(s string) bool {
+	token := p.peek()
+	if token.kind == tokenSymbol && token.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) ident() (string, error) {
+	token := p.next()
+	if token.kind != tokenIdent {
+		return "", i18n.Errorf(i18n.QueryExpectedName, token.text)
+	}
+	return token.text, nil
+}
+
+func (p *queryParser) parseSelect() (*Query, error) {
+	query := &Query{limit: -1}
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+	if !p.symbol("*") {
+		for {
+			field, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			query.fields = append(query.fields, field)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if err := p.expectKeyword("from"); err != nil {
+		return nil, err
+	}
+	path, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(path, ".")
+	if len(parts) != 3 {
+		return nil, i18n.Errorf(i18n.QueryCollectionPath, path)
+	}
+	query.pool, query.schema, query.collection = parts[0], parts[1], parts[2]
+
+	if p.keyword("where") {
+		if query.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("order") {
+		if err := p.expectKeyword("by"); err != nil {
+			return nil, err
+		}
+		for {
+			field, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			order := orderField{field: field}
+			if p.keyword("desc") {
+				order.descending = true
+			} else {
+				p.keyword("asc")
+			}
+			query.orderBy = append(query.orderBy, order)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("limit") {
+		if query.limit, err = p.count(); err != nil {
+			return nil, err
+		}
+		if p.keyword("offset") {
+			if query.offset, err = p.count(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if token := p.peek(); token.kind != tokenEOF {
+		return nil, i18n.Errorf(i18n.QueryUnexpectedToken, token.text)
+	}
+	return query, nil
+}
+
+func (p *queryParser) count() (int, error) {
+	token := p.next()
+	n, err := strconv.Atoi(token.text)
+	if token.kind != tokenNumber || err != nil || n < 0 {
+		return 0, i18n.Errorf(i18n.QueryExpectedCount, token.text)
+	}
+	return n, nil
+}
+
+func (p *queryParser) parseOr() (queryCondition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryCondition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryCondition, error) {
+	if p.keyword("not") {
+		condition, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notCondition{condition}, nil
+	}
+	if p.symbol("(") {
+		condition, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, i18n.Errorf(i18n.QueryExpectedParen, p.peek().text)
+		}
+		return condition, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (queryCondition, error) {
+	field, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("is") {
+		negate := p.keyword("not")
+		if err := p.expectKeyword("null"); err != nil {
+			return nil, err
+		}
+		return &nullCondition{field: field, negate: negate}, nil
+	}
+	negate := p.keyword("not")
+	if p.keyword("like") {
+		token := p.next()
+		if token.kind != tokenString {
+			return nil, i18n.Errorf(i18n.QueryExpectedPattern, token.text)
+		}
+		return &likeCondition{field: field, pattern: token.text, negate: negate}, nil
+	}
+	if negate {
+		return nil, i18n.Errorf(i18n.QueryExpectedLike, p.peek().text)
+	}
+
+	token := p.next()
+	op := token.text
+	if token.kind != tokenSymbol || !strings.Contains(" = != <> < <= > >= ", " "+op+" ") {
+		return nil, i18n.Errorf(i18n.QueryExpectedOperator, op)
+	}
+	if op == "<>" {
+		op = "!="
+	}
+	literal, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{field: field, op: op, literal: literal}, nil
+}
+
+func (p *queryParser) literal() (interface{}, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenNumber:
+		return parseNumber(token.text)
+	case tokenString:
+		return token.text, nil
+	case tokenIdent:
+		switch strings.ToLower(token.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return nil, i18n.Errorf(i18n.QueryExpectedLiteral, token.text)
+}
@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"db/tree"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		text   string
+		fields []string
+		order  []orderField
+		limit  int
+		offset int
+		fail   bool
+	}{
+		{text: "select * from p.s.c", limit: -1},
+		{text: "SELECT a, b.c FROM p.s.c", fields: []string{"a", "b.c"}, limit: -1},
+		{text: "select * from p.s.c where a = 1 and (b != 'x' or not c is null)", limit: -1},
+		{text: "select * from p.s.c where a not like 'x%' order by a desc, b limit 5 offset 2",
+			order: []orderField{{field: "a", descending: true}, {field: "b"}}, limit: 5, offset: 2},
+		{text: "select * from p.s.c where a <> -1.5e3 and b >= \"q\" and c is not null", limit: -1},
+		{text: "select * from p.s.c limit 0", limit: 0},
+
+		{text: "", fail: true},
+		{text: "select from p.s.c", fail: true},
+		{text: "select * from p.s", fail: true},
+		{text: "select * p.s.c", fail: true},
+		{text: "select * from p.s.c where", fail: true},
+		{text: "select * from p.s.c where a", fail: true},
+		{text: "select * from p.s.c where a = ", fail: true},
+		{text: "select * from p.s.c where a = b", fail: true},
+		{text: "select * from p.s.c where a not = 1", fail: true},
+		{text: "select * from p.s.c where a like 1", fail: true},
+		{text: "select * from p.s.c where (a = 1", fail: true},
+		{text: "select * from p.s.c where a = 'x", fail: true},
+		{text: "select * from p.s.c where a ~ 1", fail: true},
+		{text: "select * from p.s.c order a", fail: true},
+		{text: "select * from p.s.c limit -1", fail: true},
+		{text: "select * from p.s.c limit 1 offset x", fail: true},
+		{text: "select * from p.s.c extra", fail: true},
+	}
+	for _, test := range tests {
+		query, err := ParseQuery(test.text)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%q: ожидалась ошибка", test.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.text, err)
+			continue
+		}
+		if query.pool != "p" || query.schema != "s" || query.collection != "c" {
+			t.Errorf("%q: коллекция %s.%s.%s", test.text, query.pool, query.schema, query.collection)
+		}
+		if !reflect.DeepEqual(query.fields, test.fields) || !reflect.DeepEqual(query.orderBy, test.order) ||
+			query.limit != test.limit || query.offset != test.offset {
+			t.Errorf("%q: поля %v, порядок %v, limit %d, offset %d", test.text, query.fields, query.orderBy, query.limit, query.offset)
+		}
+	}
+}
+
+// queryRecords записи с полем n разных видов, в том числе без поля и со значением null.
+var queryRecords = map[string]string{
+	"a": `{"n":1}`,
+	"b": `{"n":2.5}`,
+	"c": `{"n":-3}`,
+	"d": `{"n":"x"}`,
+	"e": `{"n":"y","m":1}`,
+	"f": `{"n":true}`,
+	"g": `{"n":null}`,
+	"h": `{"m":2}`,
+	"i": `{}`,
+	"j": `7`,
+	"k": `{"n":{"v":1}}`,
+	"l": `{"n":1,"n.v":5}`,
+	"m": `{"_value":1,"_key":"z"}`,
+}
+
+func TestQueryPlannedMatchesFullScan(t *testing.T) {
+	collection := NewVersionedCollection(tree.NewTreeCollection("avl"))
+	for key, text := range queryRecords {
+		value, err := ParseValue(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := collection.Insert(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, field := range []string{"n", "m", "n.v", "_value"} {
+		if err := collection.CreateIndex(field, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		where   string
+		planned bool // запрос должен читаться через индекс
+	}{
+		{"n = 1", true},
+		{"n = 1.0", true},
+		{"n = 'x'", true},
+		{"n = true", true},
+		{"n > 1", true},
+		{"n >= -3", true},
+		{"n < 'y'", true},
+		{"n <= 2.5", true},
+		{"n > true", true},
+		{"m = 1 and n = 'y'", true},
+		{"n = null", false},
+		{"n != null", false},
+		{"n is null", false},
+		{"n.v = 1", false},
+		{"_value = 1", false},
+		{"n != 1", false},
+		{"n = 1 or m = 2", false},
+		{"_key >= 'c' and _key < 'h'", false},
+	}
+	for _, test := range tests {
+		query, err := ParseQuery("select * from p.s.c where " + test.where)
+		if err != nil {
+			t.Fatalf("%s: %v", test.where, err)
+		}
+		_, _, _, _, planned := indexBounds(collection, splitConjuncts(query.where))
+		if planned != test.planned {
+			t.Errorf("%s: чтение через индекс %v, ожидалось %v", test.where, planned, test.planned)
+		}
+		// Полный просмотр без планировщика
+		var want []string
+		collection.ForEach(func(key string, value interface{}) bool {
+			if query.where.eval(key, value) {
+				want = append(want, key)
+			}
+			return true
+		})
+		for _, source := range []tree.Collection{collection, snapshotView(t, collection)} {
+			records, err := query.Execute(source)
+			if err != nil {
+				t.Fatalf("%s: %v", test.where, err)
+			}
+			got := []string{}
+			for _, record := range records {
+				got = append(got, record.Key)
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s (%T): %v, полный просмотр дает %v", test.where, source, got, want)
+			}
+		}
+	}
+}
+
+// snapshotView возвращает срез коллекции на текущий момент.
+func snapshotView(t *testing.T, collection *VersionedCollection) tree.Collection {
+	t.Helper()
+	return (&Snapshot{time: collection.commits.stable()}).View(collection)
+}
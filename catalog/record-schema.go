@@ -0,0 +1,206 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"db/i18n"
+)
+
+// Типы полей записи.
+const (
+	FieldString = "string"
+	FieldInt    = "int"
+	FieldFloat  = "float"
+	FieldBool   = "bool"
+	FieldObject = "object"
+	FieldArray  = "array"
+	FieldAny    = "any"
+)
+
+// Field описание поля структурированной записи.
+type Field struct {
+	Name       string
+	Type       string
+	Required   bool
+	Default    interface{}
+	HasDefault bool
+}
+
+// RecordSchema описание полей записей коллекции. Записи такой коллекции
+// должны быть объектами, содержащими только описанные поля.
+type RecordSchema struct {
+	fields []Field
+}
+
+// ParseRecordSchema разбирает описание полей вида
+//
+//	[{"name": "age", "type": "int", "required": true, "default": 0}, ...]
+func ParseRecordSchema(text string) (*RecordSchema, error) {
+	value, err := ParseValue(text)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, i18n.Errorf(i18n.FieldsNotArray)
+	}
+	fields := make([]Field, 0, len(items))
+	for _, item := range items {
+		definition, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, i18n.Errorf(i18n.FieldNotObject, FormatValue(item))
+		}
+		field, err := parseField(definition)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return NewRecordSchema(fields)
+}
+
+// NewRecordSchema создает описание полей, проверяя его корректность.
+func NewRecordSchema(fields []Field) (*RecordSchema, error) {
+	names := make(map[string]bool)
+	for i, field := range fields {
+		if field.Name == "" {
+			return nil, i18n.Errorf(i18n.FieldNameMissing)
+		}
+		if names[field.Name] {
+			return nil, i18n.Errorf(i18n.FieldDuplicate, field.Name)
+		}
+		names[field.Name] = true
+		if !isFieldType(field.Type) {
+			return nil, i18n.Errorf(i18n.UnknownFieldType, field.Name, field.Type)
+		}
+		if field.HasDefault && field.Default != nil {
+			value, err := checkFieldType(field, field.Default)
+			if err != nil {
+				return nil, err
+			}
+			fields[i].Default = value
+		}
+	}
+	return &RecordSchema{fields: fields}, nil
+}
+
+func parseField(definition map[string]interface{}) (Field, error) {
+	var field Field
+	for key, value := range definition {
+		switch key {
+		case "name":
+			name, ok := value.(string)
+			if !ok {
+				return field, i18n.Errorf(i18n.FieldNameNotString)
+			}
+			field.Name = name
+		case "type":
+			fieldType, ok := value.(string)
+			if !ok {
+				return field, i18n.Errorf(i18n.FieldTypeNotString)
+			}
+			field.Type = fieldType
+		case "required":
+			required, ok := value.(bool)
+			if !ok {
+				return field, i18n.Errorf(i18n.FieldRequiredNotBool)
+			}
+			field.Required = required
+		case "default":
+			field.Default = value
+			field.HasDefault = true
+		default:
+			return field, i18n.Errorf(i18n.UnknownFieldAttribute, key)
+		}
+	}
+	if field.Type == "" {
+		field.Type = FieldAny
+	}
+	return field, nil
+}
+
+func isFieldType(fieldType string) bool {
+	switch fieldType {
+	case FieldString, FieldInt, FieldFloat, FieldBool, FieldObject, FieldArray, FieldAny:
+		return true
+	}
+	return false
+}
+
+// Fields возвращает описания полей в порядке объявления.
+func (rs *RecordSchema) Fields() []Field {
+	return rs.fields
+}
+
+// Validate проверяет запись и возвращает ее копию с подставленными значениями
+// по умолчанию для отсутствующих полей.
+func (rs *RecordSchema) Validate(value interface{}) (interface{}, error) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, i18n.Errorf(i18n.RecordNotObject, FormatValue(value))
+	}
+	known := make(map[string]bool, len(rs.fields))
+	result := make(map[string]interface{}, len(rs.fields))
+	for _, field := range rs.fields {
+		known[field.Name] = true
+		fieldValue, exists := record[field.Name]
+		if !exists || fieldValue == nil {
+			switch {
+			case field.HasDefault:
+				result[field.Name] = field.Default
+			case field.Required:
+				return nil, i18n.Errorf(i18n.RecordMissingField, field.Name)
+			case exists:
+				result[field.Name] = nil
+			}
+			continue
+		}
+		checked, err := checkFieldType(field, fieldValue)
+		if err != nil {
+			return nil, i18n.Wrap(err, i18n.InvalidRecord, err)
+		}
+		result[field.Name] = checked
+	}
+	var unknown []string
+	for name := range record {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, i18n.Errorf(i18n.RecordUnknownFields, strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
+
+// checkFieldType проверяет тип значения поля; целые числа допускаются в полях float.
+func checkFieldType(field Field, value interface{}) (interface{}, error) {
+	ok := false
+	switch field.Type {
+	case FieldString:
+		_, ok = value.(string)
+	case FieldInt:
+		_, ok = value.(int64)
+	case FieldFloat:
+		switch v := value.(type) {
+		case float64:
+			ok = true
+		case int64:
+			return float64(v), nil
+		}
+	case FieldBool:
+		_, ok = value.(bool)
+	case FieldObject:
+		_, ok = value.(map[string]interface{})
+	case FieldArray:
+		_, ok = value.([]interface{})
+	case FieldAny:
+		ok = true
+	}
+	if !ok {
+		return nil, i18n.Errorf(i18n.FieldTypeMismatch, field.Name, field.Type, FormatValue(value))
+	}
+	return value, nil
+}
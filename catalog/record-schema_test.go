@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+
+	"db/i18n"
+)
+
+// hasMessage проверяет, что err или одна из оборачиваемых ею ошибок имеет сообщение id.
+func hasMessage(err error, id i18n.ID) bool {
+	for err != nil {
+		var message *i18n.Error
+		if !errors.As(err, &message) {
+			return false
+		}
+		if message.ID == id {
+			return true
+		}
+		err = message.Err
+	}
+	return false
+}
+
+func TestRecordSchemaValidate(t *testing.T) {
+	const fields = `[{"name": "name", "type": "string", "required": true},
+		{"name": "age", "type": "int"},
+		{"name": "score", "type": "float", "default": 0},
+		{"name": "active", "type": "bool", "default": true},
+		{"name": "tags", "type": "array"},
+		{"name": "address", "type": "object"},
+		{"name": "extra"}]`
+	schema, err := ParseRecordSchema(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		record string
+		want   string // запись после проверки в синтаксисе FormatValue
+		err    i18n.ID
+	}{
+		{record: `{"name": "a"}`, want: `{"active": true, "name": "a", "score": 0.0}`},
+		{record: `{"name": "a", "age": 3, "score": 2, "active": false}`,
+			want: `{"active": false, "age": 3, "name": "a", "score": 2.0}`},
+		{record: `{"name": "a", "age": null, "tags": [1], "address": {"city": "x"}, "extra": [true]}`,
+			want: `{"active": true, "address": {"city": "x"}, "age": null, "extra": [true], "name": "a", "score": 0.0, "tags": [1]}`},
+		{record: `{"name": "a", "score": null}`, want: `{"active": true, "name": "a", "score": 0.0}`},
+
+		// Обязательное поле отсутствует или равно null
+		{record: `{"age": 1}`, err: i18n.RecordMissingField},
+		{record: `{"name": null}`, err: i18n.RecordMissingField},
+		// Значение неподходящего типа
+		{record: `{"name": 1}`, err: i18n.FieldTypeMismatch},
+		{record: `{"name": "a", "age": 1.5}`, err: i18n.FieldTypeMismatch},
+		{record: `{"name": "a", "age": "1"}`, err: i18n.FieldTypeMismatch},
+		{record: `{"name": "a", "score": "high"}`, err: i18n.FieldTypeMismatch},
+		{record: `{"name": "a", "active": 1}`, err: i18n.FieldTypeMismatch},
+		{record: `{"name": "a", "tags": {}}`, err: i18n.FieldTypeMismatch},
+		{record: `{"name": "a", "address": []}`, err: i18n.FieldTypeMismatch},
+		// Неописанные поля и записи, не являющиеся объектами
+		{record: `{"name": "a", "other": 1}`, err: i18n.RecordUnknownFields},
+		{record: `[1]`, err: i18n.RecordNotObject},
+		{record: `"a"`, err: i18n.RecordNotObject},
+	}
+	for _, test := range tests {
+		record, err := ParseValue(test.record)
+		if err != nil {
+			t.Fatalf("%s: %v", test.record, err)
+		}
+		got, err := schema.Validate(record)
+		if test.err != "" {
+			if !hasMessage(err, test.err) {
+				t.Errorf("%s: ошибка %v, ожидалась %s", test.record, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.record, err)
+			continue
+		}
+		if text := FormatValue(got); text != test.want {
+			t.Errorf("%s: %s, ожидалось %s", test.record, text, test.want)
+		}
+	}
+}
+
+func TestParseRecordSchemaErrors(t *testing.T) {
+	tests := []struct {
+		fields string
+		err    i18n.ID
+	}{
+		{`{"name": "a"}`, i18n.FieldsNotArray},
+		{`[1]`, i18n.FieldNotObject},
+		{`[{"type": "int"}]`, i18n.FieldNameMissing},
+		{`[{"name": "a"}, {"name": "a"}]`, i18n.FieldDuplicate},
+		{`[{"name": "a", "type": "date"}]`, i18n.UnknownFieldType},
+		{`[{"name": 1}]`, i18n.FieldNameNotString},
+		{`[{"name": "a", "type": 1}]`, i18n.FieldTypeNotString},
+		{`[{"name": "a", "required": "yes"}]`, i18n.FieldRequiredNotBool},
+		{`[{"name": "a", "size": 1}]`, i18n.UnknownFieldAttribute},
+		{`[{"name": "a", "type": "int", "default": "x"}]`, i18n.FieldTypeMismatch},
+	}
+	for _, test := range tests {
+		if _, err := ParseRecordSchema(test.fields); !hasMessage(err, test.err) {
+			t.Errorf("%s: ошибка %v, ожидалась %s", test.fields, err, test.err)
+		}
+	}
+}
+
+func TestCollectionRejectsInvalidRecords(t *testing.T) {
+	schema, err := ParseRecordSchema(`[{"name": "n", "type": "int", "required": true}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools := newTestPools(t, "c")
+	coll, _ := pools.LookupCollection("p", "s", "c")
+	coll.SetRecordSchema(schema)
+	if err := coll.Insert("k", map[string]interface{}{"n": int64(1)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []interface{}{
+		map[string]interface{}{"n": "one"},
+		map[string]interface{}{},
+		int64(1),
+	} {
+		if err := coll.Insert("x", value); err == nil {
+			t.Errorf("вставлена запись %s", FormatValue(value))
+		}
+		if err := coll.Update("k", value); err == nil {
+			t.Errorf("запись изменена на %s", FormatValue(value))
+		}
+	}
+	// Отклоненные изменения не попадают ни в коллекцию, ни в историю
+	if _, err := coll.Get("x"); err == nil {
+		t.Fatal("отклоненная запись x в коллекции")
+	}
+	if versions := coll.History().Versions("k"); len(versions) != 1 {
+		t.Fatalf("история k содержит %d версий", len(versions))
+	}
+}
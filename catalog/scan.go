@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"strings"
+
+	"db/tree"
+)
+
+// DefaultPageSize размер страницы по умолчанию для постраничного чтения.
+const DefaultPageSize = 100
+
+// ScanPrefix возвращает до limit записей с ключами, начинающимися с prefix,
+// в порядке возрастания ключей, начиная с ключа, следующего за after
+// (пустой after — с начала). Второе значение — токен продолжения: ключ последней
+// возвращенной записи, если после нее остались записи с тем же префиксом,
+// иначе пустая строка. Токен передается как after для чтения следующей страницы.
+func ScanPrefix(collection tree.Collection, prefix string, limit int, after string) ([]tree.Record, string) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	cursor := collection.Cursor()
+	defer cursor.Close()
+
+	start := prefix
+	if after > start {
+		start = after
+	}
+	valid := cursor.Seek(start)
+	if valid && after != "" && cursor.Key() == after {
+		valid = cursor.Next()
+	}
+
+	var records []tree.Record
+	for ; valid && strings.HasPrefix(cursor.Key(), prefix); valid = cursor.Next() {
+		if len(records) == limit {
+			return records, records[len(records)-1].Key
+		}
+		records = append(records, tree.Record{Key: cursor.Key(), Value: cursor.Value()})
+	}
+	return records, ""
+}
+
+// ScanRange возвращает записи с ключами из диапазона [lower, upper] в порядке
+// возрастания ключей. Граница nil не ограничивает диапазон с этой стороны.
+func ScanRange(collection tree.Collection, lower, upper *string) ([]tree.Record, error) {
+	var records []tree.Record
+	err := scanKeyRange(collection, lower, upper, func(key string, value interface{}) bool {
+		records = append(records, tree.Record{Key: key, Value: value})
+		return true
+	})
+	return records, err
+}
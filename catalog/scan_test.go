@@ -0,0 +1,115 @@
+package catalog
+
+import (
+	"fmt"
+	"testing"
+
+	"db/tree"
+)
+
+// scanKeys ключи коллекции для постраничного чтения: префиксы "a" и "ab"
+// окружены ключами, которые меньше и больше любого ключа с префиксом.
+var scanKeys = []string{"", "0", "a", "a0", "a1", "a2", "a3", "ab", "aba", "abb", "ac", "b", "b0"}
+
+func keysOf(records []tree.Record) []string {
+	keys := []string{}
+	for _, record := range records {
+		keys = append(keys, record.Key)
+	}
+	return keys
+}
+
+func TestScanPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		limit  int
+		after  string
+		keys   []string
+		next   string
+	}{
+		{prefix: "a", limit: 3, keys: []string{"a", "a0", "a1"}, next: "a1"},
+		{prefix: "a", limit: 3, after: "a1", keys: []string{"a2", "a3", "ab"}, next: "ab"},
+		{prefix: "a", limit: 3, after: "ab", keys: []string{"aba", "abb", "ac"}},
+		// Ровно limit оставшихся записей: токен не выдается
+		{prefix: "a", limit: 9, keys: []string{"a", "a0", "a1", "a2", "a3", "ab", "aba", "abb", "ac"}},
+		{prefix: "a", limit: 8, keys: []string{"a", "a0", "a1", "a2", "a3", "ab", "aba", "abb"}, next: "abb"},
+		{prefix: "ab", limit: 3, keys: []string{"ab", "aba", "abb"}},
+		{prefix: "ab", limit: 2, keys: []string{"ab", "aba"}, next: "aba"},
+		{prefix: "a", limit: 1, after: "abb", keys: []string{"ac"}},
+		{prefix: "a", limit: 1, after: "ac", keys: []string{}},
+		// after отсутствует в коллекции
+		{prefix: "a", limit: 2, after: "a05", keys: []string{"a1", "a2"}, next: "a2"},
+		{prefix: "a", limit: 2, after: "abc", keys: []string{"ac"}},
+		// after вне префикса: меньше всех ключей с префиксом и больше всех
+		{prefix: "a", limit: 2, after: "0", keys: []string{"a", "a0"}, next: "a0"},
+		{prefix: "a", limit: 2, after: "", keys: []string{"a", "a0"}, next: "a0"},
+		{prefix: "ab", limit: 5, after: "a3", keys: []string{"ab", "aba", "abb"}},
+		{prefix: "a", limit: 2, after: "b", keys: []string{}},
+		{prefix: "a", limit: 2, after: "\xff", keys: []string{}},
+		// Пустой префикс и префикс без записей
+		{prefix: "", limit: 3, keys: []string{"", "0", "a"}, next: "a"},
+		{prefix: "", limit: 2, after: "ac", keys: []string{"b", "b0"}},
+		{prefix: "c", limit: 2, keys: []string{}},
+		{prefix: "a00", limit: 2, keys: []string{}},
+		// Неположительный limit — страница по умолчанию
+		{prefix: "a", limit: 0, keys: []string{"a", "a0", "a1", "a2", "a3", "ab", "aba", "abb", "ac"}},
+	}
+	for _, collectionType := range collectionTypes {
+		t.Run(collectionType, func(t *testing.T) {
+			collection, err := tree.NewCollection(collectionType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			versioned := NewVersionedCollection(collection)
+			for _, key := range scanKeys {
+				if err := versioned.Insert(key, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, source := range []tree.Collection{versioned, snapshotView(t, versioned)} {
+				for _, test := range tests {
+					records, next := ScanPrefix(source, test.prefix, test.limit, test.after)
+					if fmt.Sprint(keysOf(records)) != fmt.Sprint(test.keys) || next != test.next {
+						t.Errorf("%T: префикс %q, limit %d, after %q: %q, токен %q, ожидалось %q, токен %q",
+							source, test.prefix, test.limit, test.after, keysOf(records), next, test.keys, test.next)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestScanPrefixPages(t *testing.T) {
+	collection := NewVersionedCollection(tree.NewTreeCollection("btree"))
+	var want []string
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("p%03d", i)
+		want = append(want, key)
+		if err := collection.Insert(key, int64(i)); err != nil {
+			t.Fatal(err)
+		}
+		collection.Insert(fmt.Sprintf("q%03d", i), int64(i))
+	}
+	for limit := 1; limit <= 101; limit++ {
+		var got []string
+		pages, after := 0, ""
+		for {
+			records, next := ScanPrefix(collection, "p", limit, after)
+			got = append(got, keysOf(records)...)
+			pages++
+			if next == "" {
+				break
+			}
+			if pages > len(want) {
+				t.Fatalf("limit %d: чтение не завершается", limit)
+			}
+			after = next
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("limit %d: прочитано %v", limit, got)
+		}
+		if wantPages := (len(want) + limit - 1) / limit; pages != wantPages {
+			t.Errorf("limit %d: %d страниц, ожидалось %d", limit, pages, wantPages)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package catalog
+
+// Формат хранения данных на диске.
+//
+// Все пулы сохраняются в один файл pools.json в каталоге данных (флаг -data-dir).
+// Файл содержит JSON-объект следующего вида:
+//
+//	{
+//	  "version": 1,
+//	  "lsn": <номер последней команды журнала, вошедшей в файл>,
+//	  "pools": {
+//	    "<пул>": {
+//	      "schemas": {
+//	        "<схема>": {
+//	          "collections": {
+//	            "<коллекция>": {
+//	              "type": "map" | "avl" | "btree" | "redblack",
+//	              "degree": <минимальная степень, только для btree>,
+//	              "fields": [{"name": "<поле>", "type": "<тип>", "required": <bool>, "default": <значение>}, ...],
+//	              "indexes": [{"field": "<поле>", "type": "avl" | "btree"}, ...],
+//	              "records": [{"key": "<ключ>", "value": <значение>}, ...],
+//	              "history": {
+//	                "<ключ>": [{"time": "<RFC3339Nano>", "value": <значение>,
+//	                            "previous": <замененное значение>, "deleted": <bool>}, ...]
+//	              }
+//	            }
+//	          }
+//	        }
+//	      }
+//	    }
+//	  }
+//	}
+//
+// Записи (records) перечислены в порядке возрастания ключей и задают текущее
+// состояние коллекции, history — историю изменений для чтения на момент времени.
+// Значения записываются в синтаксисе значений команд (см. values.go), который
+// является подмножеством JSON и сохраняет различие целых и дробных чисел.
+// Файл перезаписывается атомарно: данные пишутся во временный файл pools.json.tmp,
+// сбрасываются на диск и переименовываются поверх pools.json.
+//
+// Изменения, сделанные после сохранения pools.json, хранятся в журнале
+// упреждающей записи wal.log (см. engine/wal.go): по одной JSON-строке на команду
+// вида {"lsn": <номер>, "time": "<RFC3339Nano>", "session": <номер сеанса>,
+// "command": "<текст команды>"}. Команда записывается до выполнения; если она
+// завершилась ошибкой, за ней следует строка {"lsn": <номер>, "session": <номер сеанса>,
+// "failed": <номер команды>}. При запуске к pools.json применяются команды
+// журнала с номером больше lsn, после чего создается новая контрольная точка
+// и журнал очищается.
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"db/i18n"
+	"db/tree"
+)
+
+const (
+	storageFileName = "pools.json"
+	storageVersion  = 1
+)
+
+type storedPools struct {
+	Version int                    `json:"version"`
+	LSN     uint64                 `json:"lsn"`
+	Pools   map[string]*storedPool `json:"pools"`
+}
+
+type storedPool struct {
+	Schemas map[string]*storedSchema `json:"schemas"`
+}
+
+type storedSchema struct {
+	Collections map[string]*storedCollection `json:"collections"`
+}
+
+type storedCollection struct {
+	Type    string                     `json:"type"`
+	Degree  int                        `json:"degree,omitempty"`
+	Fields  []storedField              `json:"fields,omitempty"`
+	Indexes []storedIndex              `json:"indexes,omitempty"`
+	Records []storedRecord             `json:"records"`
+	History map[string][]storedVersion `json:"history,omitempty"`
+}
+
+type storedField struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Required bool            `json:"required,omitempty"`
+	Default  json.RawMessage `json:"default,omitempty"`
+}
+
+// storedIndex описание вторичного индекса; сам индекс строится заново при загрузке.
+type storedIndex struct {
+	Field string `json:"field"`
+	Type  string `json:"type"`
+}
+
+type storedRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type storedVersion struct {
+	Time     time.Time       `json:"time"`
+	Value    json.RawMessage `json:"value"`
+	Previous json.RawMessage `json:"previous,omitempty"`
+	Deleted  bool            `json:"deleted,omitempty"`
+}
+
+// LoadCheckpoint читает контрольную точку из каталога данных dataDir и возвращает
+// восстановленные пулы и номер последней вошедшей в нее команды журнала.
+// Если контрольной точки еще нет, возвращаются пустые пулы.
+func LoadCheckpoint(dataDir string) (*AllPools, uint64, error) {
+	pools := InitPool()
+	data, err := os.ReadFile(filepath.Join(dataDir, storageFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return pools, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	var stored storedPools
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, 0, i18n.Wrap(err, i18n.DataFileRead, err)
+	}
+	if stored.Version != storageVersion {
+		return nil, 0, i18n.Errorf(i18n.DataFileVersion, stored.Version)
+	}
+	if err := pools.restore(&stored); err != nil {
+		return nil, 0, err
+	}
+	return pools, stored.LSN, nil
+}
+
+// WriteCheckpoint атомарно записывает все пулы в каталог данных dataDir вместе
+// с номером lsn последней вошедшей в контрольную точку команды журнала.
+// Вызывающий должен не допускать изменений, еще не записанных в журнал.
+func (pools *AllPools) WriteCheckpoint(dataDir string, lsn uint64) error {
+	stored := pools.dump()
+	stored.LSN = lsn
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dataDir, storageFileName), data)
+}
+
+// writeFileAtomic записывает файл через временный файл и переименование,
+// чтобы при сбое на диске оставалась либо старая, либо новая версия.
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (pools *AllPools) dump() *storedPools {
+	pools.mu.RLock()
+	defer pools.mu.RUnlock()
+	stored := &storedPools{Version: storageVersion, Pools: make(map[string]*storedPool)}
+	for poolName, pool := range pools.pools {
+		stored.Pools[poolName] = pool.dump()
+	}
+	return stored
+}
+
+func (pool *Pool) dump() *storedPool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	sp := &storedPool{Schemas: make(map[string]*storedSchema)}
+	for schemaName, schema := range pool.schema {
+		sp.Schemas[schemaName] = schema.dump()
+	}
+	return sp
+}
+
+func (schema *Schema) dump() *storedSchema {
+	schema.mu.RLock()
+	defer schema.mu.RUnlock()
+	ss := &storedSchema{Collections: make(map[string]*storedCollection)}
+	for collectionName, collection := range schema.collection {
+		ss.Collections[collectionName] = dumpCollection(collection)
+	}
+	return ss
+}
+
+// dumpCollection сохраняет коллекцию под блокировкой чтения, чтобы записи,
+// индексы и история в контрольной точке соответствовали друг другу.
+func dumpCollection(collection *VersionedCollection) *storedCollection {
+	collection.mu.RLock()
+	defer collection.mu.RUnlock()
+	collectionType, degree := describeCollection(collection.collection)
+	sc := &storedCollection{
+		Type:    collectionType,
+		Degree:  degree,
+		Records: []storedRecord{},
+		History: make(map[string][]storedVersion),
+	}
+	if fields := collection.fields; fields != nil {
+		for _, field := range fields.Fields() {
+			stored := storedField{Name: field.Name, Type: field.Type, Required: field.Required}
+			if field.HasDefault {
+				stored.Default = dumpValue(field.Default)
+			}
+			sc.Fields = append(sc.Fields, stored)
+		}
+	}
+	for _, index := range collection.sortedIndexes() {
+		sc.Indexes = append(sc.Indexes, storedIndex{Field: index.Field(), Type: index.Type()})
+	}
+	collection.collection.ForEach(func(key string, value interface{}) bool {
+		sc.Records = append(sc.Records, storedRecord{Key: key, Value: dumpValue(value)})
+		return true
+	})
+	collection.history.mu.RLock()
+	defer collection.history.mu.RUnlock()
+	for key, versions := range collection.history.versions {
+		for _, version := range versions {
+			stored := storedVersion{
+				Time:    version.Time,
+				Value:   dumpValue(version.Value),
+				Deleted: version.Deleted,
+			}
+			if version.Previous != nil {
+				stored.Previous = dumpValue(version.Previous)
+			}
+			sc.History[key] = append(sc.History[key], stored)
+		}
+	}
+	return sc
+}
+
+// describeCollection возвращает тип коллекции и, для B-дерева, его минимальную степень.
+func describeCollection(collection tree.Collection) (string, int) {
+	switch c := collection.(type) {
+	case *tree.TreeCollection:
+		switch t := c.Tree().(type) {
+		case *tree.BTree:
+			return "btree", t.Degree()
+		case *tree.RedBlackTree:
+			return "redblack", 0
+		default:
+			return "avl", 0
+		}
+	case *tree.AVLCollection:
+		return "avl", 0
+	default:
+		return "map", 0
+	}
+}
+
+func (pools *AllPools) restore(stored *storedPools) error {
+	for poolName, sp := range stored.Pools {
+		pool := NewPool()
+		for schemaName, ss := range sp.Schemas {
+			schema := InitSchema()
+			for collectionName, sc := range ss.Collections {
+				collection, err := restoreCollection(sc)
+				if err != nil {
+					return i18n.Wrap(err, i18n.CollectionRestore, poolName, schemaName, collectionName, err)
+				}
+				schema.collection[collectionName] = collection
+			}
+			pool.schema[schemaName] = schema
+		}
+		pool.setCommits(pools.commits)
+		pools.pools[poolName] = pool
+	}
+	return nil
+}
+
+func restoreCollection(sc *storedCollection) (*VersionedCollection, error) {
+	var collection tree.Collection
+	var err error
+	if sc.Type == "btree" && sc.Degree != 0 {
+		collection, err = tree.NewBTreeCollection(sc.Degree)
+	} else {
+		collection, err = tree.NewCollection(sc.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Записи вставляются напрямую, минуя историю: она восстанавливается отдельно
+	for _, record := range sc.Records {
+		value, err := restoreValue(record.Value)
+		if err != nil {
+			return nil, err
+		}
+		if err := collection.Insert(record.Key, value); err != nil {
+			return nil, err
+		}
+	}
+	versioned := NewVersionedCollection(collection)
+	if sc.Fields != nil {
+		fields := make([]Field, 0, len(sc.Fields))
+		for _, stored := range sc.Fields {
+			field := Field{Name: stored.Name, Type: stored.Type, Required: stored.Required}
+			if stored.Default != nil {
+				field.HasDefault = true
+				if field.Default, err = restoreValue(stored.Default); err != nil {
+					return nil, err
+				}
+			}
+			fields = append(fields, field)
+		}
+		recordSchema, err := NewRecordSchema(fields)
+		if err != nil {
+			return nil, err
+		}
+		versioned.SetRecordSchema(recordSchema)
+	}
+	for _, index := range sc.Indexes {
+		if err := versioned.CreateIndex(index.Field, index.Type); err != nil {
+			return nil, err
+		}
+	}
+	for key, versions := range sc.History {
+		for _, stored := range versions {
+			version := Version{Time: stored.Time, Deleted: stored.Deleted}
+			if version.Value, err = restoreValue(stored.Value); err != nil {
+				return nil, err
+			}
+			if version.Previous, err = restoreValue(stored.Previous); err != nil {
+				return nil, err
+			}
+			versioned.history.Record(key, version)
+		}
+	}
+	return versioned, nil
+}
+
+func dumpValue(value interface{}) json.RawMessage {
+	return json.RawMessage(FormatValue(value))
+}
+
+func restoreValue(raw json.RawMessage) (interface{}, error) {
+	text := strings.TrimSpace(string(raw))
+	if text == "" {
+		return nil, nil
+	}
+	return ParseValue(text)
+}
@@ -0,0 +1,302 @@
+package catalog
+
+import (
+	"sort"
+	"time"
+
+	"db/i18n"
+)
+
+// Виды операций транзакции.
+const (
+	txInsert = "insert"
+	txUpdate = "update"
+	txRemove = "remove"
+)
+
+// txOp операция транзакции над записью коллекции пула.
+type txOp struct {
+	kind       string
+	schemaName string
+	collection string
+	key        string
+	value      interface{}
+	target     *VersionedCollection // коллекция, найденная при фиксации
+	previous   interface{}          // значение до применения операции
+}
+
+// Transaction накапливает изменения записей в нескольких коллекциях одного пула
+// и применяет их все вместе при фиксации. Все версии, созданные транзакцией,
+// получают в истории одно и то же время фиксации.
+type Transaction struct {
+	pools      *AllPools
+	poolName   string
+	ops        []txOp
+	applied    int // число примененных операций при последней фиксации
+	finished   bool
+	commitTime time.Time
+}
+
+// Begin начинает транзакцию над коллекциями пула poolName.
+func (pools *AllPools) Begin(poolName string) (*Transaction, error) {
+	if _, err := pools.GetPool(poolName); err != nil {
+		return nil, err
+	}
+	return &Transaction{pools: pools, poolName: poolName}, nil
+}
+
+// CollectionName имя коллекции внутри пула транзакции.
+type CollectionName struct {
+	Schema     string
+	Collection string
+}
+
+// Collections возвращает имена коллекций, которые изменяет транзакция, в порядке имен.
+func (tx *Transaction) Collections() []CollectionName {
+	seen := make(map[CollectionName]bool)
+	var names []CollectionName
+	for _, op := range tx.ops {
+		name := CollectionName{Schema: op.schemaName, Collection: op.collection}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Schema != names[j].Schema {
+			return names[i].Schema < names[j].Schema
+		}
+		return names[i].Collection < names[j].Collection
+	})
+	return names
+}
+
+// Pool возвращает имя пула транзакции.
+func (tx *Transaction) Pool() string {
+	return tx.poolName
+}
+
+func (tx *Transaction) add(op txOp) error {
+	if tx.finished {
+		return i18n.Errorf(i18n.TransactionFinished)
+	}
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// Insert откладывает вставку записи до фиксации.
+func (tx *Transaction) Insert(schemaName, collection, key string, value interface{}) error {
+	return tx.add(txOp{kind: txInsert, schemaName: schemaName, collection: collection, key: key, value: value})
+}
+
+// Update откладывает изменение записи до фиксации.
+func (tx *Transaction) Update(schemaName, collection, key string, value interface{}) error {
+	return tx.add(txOp{kind: txUpdate, schemaName: schemaName, collection: collection, key: key, value: value})
+}
+
+// Remove откладывает удаление записи до фиксации.
+func (tx *Transaction) Remove(schemaName, collection, key string) error {
+	return tx.add(txOp{kind: txRemove, schemaName: schemaName, collection: collection, key: key})
+}
+
+// Get возвращает значение записи с учетом еще не зафиксированных изменений транзакции.
+func (tx *Transaction) Get(schemaName, collection, key string) (interface{}, error) {
+	for i := len(tx.ops) - 1; i >= 0; i-- {
+		op := tx.ops[i]
+		if op.schemaName != schemaName || op.collection != collection || op.key != key {
+			continue
+		}
+		if op.kind == txRemove {
+			return nil, ErrKeyNotFound
+		}
+		return op.value, nil
+	}
+	coll, err := tx.pools.LookupCollection(tx.poolName, schemaName, collection)
+	if err != nil {
+		return nil, err
+	}
+	return coll.Get(key)
+}
+
+// Commit применяет все операции транзакции или, если хотя бы одна из них
+// невыполнима, не применяет ни одной. Зафиксированную транзакцию можно
+// отменить вызовом Undo, а затем применить повторно вызовом Execute.
+func (tx *Transaction) Commit() error {
+	if tx.finished {
+		return i18n.Errorf(i18n.TransactionFinished)
+	}
+	tx.finished = true
+	return tx.Execute()
+}
+
+// Rollback отменяет транзакцию, отбрасывая накопленные операции.
+func (tx *Transaction) Rollback() {
+	tx.ops = nil
+	tx.finished = true
+}
+
+// Execute применяет операции транзакции с одним временем фиксации.
+// Коллекции операций заблокированы на запись от проверки до применения последней
+// операции, поэтому другие клиенты не видят транзакцию примененной частично
+// и не могут сделать ее невыполнимой после проверки.
+func (tx *Transaction) Execute() error {
+	if err := tx.resolve(); err != nil {
+		return err
+	}
+	unlock := tx.lockTargets()
+	defer unlock()
+	if err := tx.prepare(); err != nil {
+		return err
+	}
+	tx.commitTime = tx.pools.commits.begin()
+	defer tx.pools.commits.end(tx.commitTime)
+	for tx.applied = 0; tx.applied < len(tx.ops); tx.applied++ {
+		op := &tx.ops[tx.applied]
+		if err := op.apply(tx.commitTime); err != nil {
+			// Проверка в prepare исключает ошибки; если она все же произошла,
+			// возвращаем уже примененные операции
+			tx.revert(tx.commitTime)
+			return err
+		}
+	}
+	return nil
+}
+
+// Undo отменяет все операции зафиксированной транзакции.
+func (tx *Transaction) Undo() error {
+	unlock := tx.lockTargets()
+	defer unlock()
+	t := tx.pools.commits.begin()
+	defer tx.pools.commits.end(t)
+	return tx.revert(t)
+}
+
+// revert отменяет примененные операции в обратном порядке.
+func (tx *Transaction) revert(t time.Time) error {
+	var firstErr error
+	for ; tx.applied > 0; tx.applied-- {
+		if err := tx.ops[tx.applied-1].revert(t); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// lockTargets блокирует на запись все коллекции операций в порядке их имен,
+// чтобы проверка, выбор времени фиксации и применение операций шли под одними
+// блокировками, и возвращает функцию снятия блокировок.
+func (tx *Transaction) lockTargets() func() {
+	targets := make(map[*VersionedCollection]txOp)
+	for _, op := range tx.ops {
+		if op.target != nil {
+			targets[op.target] = op
+		}
+	}
+	locked := make([]txOp, 0, len(targets))
+	for _, op := range targets {
+		locked = append(locked, op)
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		if locked[i].schemaName != locked[j].schemaName {
+			return locked[i].schemaName < locked[j].schemaName
+		}
+		return locked[i].collection < locked[j].collection
+	})
+	for _, op := range locked {
+		op.target.mu.Lock()
+	}
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			locked[i].target.mu.Unlock()
+		}
+	}
+}
+
+// resolve находит коллекции, к которым относятся операции транзакции.
+func (tx *Transaction) resolve() error {
+	pool, err := tx.pools.GetPool(tx.poolName)
+	if err != nil {
+		return err
+	}
+	for i := range tx.ops {
+		op := &tx.ops[i]
+		schema, err := pool.GetSchema(op.schemaName)
+		if err != nil {
+			return err
+		}
+		if op.target, err = schema.GetVersionedCollection(op.collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare проверяет, что все операции выполнимы в заданном порядке, не изменяя
+// данных. Вызывается под блокировками lockTargets.
+func (tx *Transaction) prepare() error {
+	type recordRef struct {
+		target *VersionedCollection
+		key    string
+	}
+	// Существование записей с учетом предыдущих операций транзакции
+	exists := make(map[recordRef]bool)
+	for i := range tx.ops {
+		op := &tx.ops[i]
+		ref := recordRef{op.target, op.key}
+		recordExists, known := exists[ref]
+		if !known {
+			_, err := op.target.collection.Get(op.key)
+			recordExists = err == nil
+		}
+		switch op.kind {
+		case txInsert:
+			if recordExists {
+				return i18n.Wrap(ErrDuplicateKey, i18n.RecordKeyExists, op.key)
+			}
+		case txUpdate, txRemove:
+			if !recordExists {
+				return i18n.Wrap(ErrKeyNotFound, i18n.RecordKeyNotFound, op.key)
+			}
+		}
+		if op.kind != txRemove {
+			if _, err := op.target.validate(op.value); err != nil {
+				return err
+			}
+		}
+		exists[ref] = op.kind != txRemove
+	}
+	return nil
+}
+
+// apply и revert вызываются под блокировкой коллекции операции.
+func (op *txOp) apply(t time.Time) error {
+	switch op.kind {
+	case txInsert:
+		return op.target.insertAt(op.key, op.value, t)
+	case txUpdate:
+		previous, err := op.target.collection.Get(op.key)
+		if err != nil {
+			return err
+		}
+		op.previous = previous
+		return op.target.updateAt(op.key, op.value, t)
+	default:
+		previous, err := op.target.collection.Get(op.key)
+		if err != nil {
+			return err
+		}
+		op.previous = previous
+		return op.target.removeAt(op.key, t)
+	}
+}
+
+func (op *txOp) revert(t time.Time) error {
+	switch op.kind {
+	case txInsert:
+		return op.target.removeAt(op.key, t)
+	case txUpdate:
+		return op.target.updateAt(op.key, op.previous, t)
+	default:
+		return op.target.insertAt(op.key, op.previous, t)
+	}
+}
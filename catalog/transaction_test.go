@@ -0,0 +1,128 @@
+package catalog
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func recordKeys(t *testing.T, snapshot *Snapshot, collection string) []string {
+	t.Helper()
+	records, err := snapshot.Records("p", "s", collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, record := range records {
+		keys = append(keys, record.Key)
+	}
+	return keys
+}
+
+func TestTransactionCommitIsAtomic(t *testing.T) {
+	pools := newTestPools(t, "a", "b")
+	a, err := pools.LookupCollection("p", "s", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var writers, readers sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("k%d", (i+j)%4)
+				tx, err := pools.Begin("p")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if j%2 == 0 {
+					tx.Insert("s", "a", key, int64(j))
+					tx.Insert("s", "b", key, int64(j))
+				} else {
+					tx.Remove("s", "a", key)
+					tx.Remove("s", "b", key)
+				}
+				// Транзакция применяется целиком или не применяется совсем
+				tx.Commit()
+				if j%3 == 0 {
+					// Одиночное изменение вне транзакции не меняет набор ключей
+					a.Update(key, int64(-j))
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 2; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				snapshot := pools.Snapshot()
+				keysA, keysB := recordKeys(t, snapshot, "a"), recordKeys(t, snapshot, "b")
+				if !reflect.DeepEqual(keysA, keysB) {
+					t.Errorf("срез на %v: в a %v, в b %v", snapshot.Time(), keysA, keysB)
+					return
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	snapshot := pools.Snapshot()
+	if keysA, keysB := recordKeys(t, snapshot, "a"), recordKeys(t, snapshot, "b"); !reflect.DeepEqual(keysA, keysB) {
+		t.Fatalf("в a %v, в b %v", keysA, keysB)
+	}
+}
+
+func TestTransactionUndo(t *testing.T) {
+	pools := newTestPools(t, "a", "b")
+	a, _ := pools.LookupCollection("p", "s", "a")
+	b, _ := pools.LookupCollection("p", "s", "b")
+	if err := a.Insert("x", int64(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := pools.Begin("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Update("s", "a", "x", int64(2))
+	tx.Insert("s", "b", "y", int64(3))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := a.Get("x"); value != int64(2) {
+		t.Fatalf("после фиксации x = %v", value)
+	}
+	if err := tx.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := a.Get("x"); value != int64(1) {
+		t.Fatalf("после отмены x = %v", value)
+	}
+	if _, err := b.Get("y"); err == nil {
+		t.Fatal("после отмены y осталась в b")
+	}
+
+	// Невыполнимая транзакция не изменяет ни одной коллекции
+	failed, _ := pools.Begin("p")
+	failed.Insert("s", "b", "z", int64(4))
+	failed.Update("s", "a", "missing", int64(5))
+	if err := failed.Commit(); err == nil {
+		t.Fatal("ожидалась ошибка фиксации")
+	}
+	if _, err := b.Get("z"); err == nil {
+		t.Fatal("запись z невыполнимой транзакции попала в b")
+	}
+}
@@ -0,0 +1,171 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"db/i18n"
+)
+
+// Синтаксис значений записей:
+//
+//	"строка с пробелами"   строка в двойных кавычках с экранированием как в JSON
+//	42, -7                 целое число (int64)
+//	3.14, 1e-3, 2.0        число с плавающей точкой (float64)
+//	true, false            логическое значение
+//	null                   пустое значение (nil)
+//	{"a": 1, "b": [2, 3]}  вложенные объекты (map[string]interface{}) и массивы ([]interface{})
+//	слово                  слово без кавычек считается строкой
+//
+// FormatValue выводит значение в том же синтаксисе, так что ParseValue(FormatValue(v))
+// возвращает значение того же типа.
+
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// ParseValue разбирает значение записи из текстового представления.
+func ParseValue(text string) (interface{}, error) {
+	switch {
+	case text == "null":
+		return nil, nil
+	case text == "true":
+		return true, nil
+	case text == "false":
+		return false, nil
+	case numberPattern.MatchString(text):
+		return parseNumber(text)
+	case strings.HasPrefix(text, `"`), strings.HasPrefix(text, "{"), strings.HasPrefix(text, "["):
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, i18n.Wrap(err, i18n.InvalidValue, text, err)
+		}
+		// После значения допустимы только пробельные символы; More не замечает
+		// лишних закрывающих скобок, например {"a":1}}
+		if strings.TrimSpace(text[decoder.InputOffset():]) != "" {
+			return nil, i18n.Errorf(i18n.TrailingCharacters, text)
+		}
+		return normalizeJSON(value)
+	default:
+		return text, nil
+	}
+}
+
+// parseNumber возвращает int64 для целых чисел и float64 для остальных.
+func parseNumber(text string) (interface{}, error) {
+	if !strings.ContainsAny(text, ".eE") {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.InvalidNumber, text)
+	}
+	return f, nil
+}
+
+// normalizeJSON заменяет json.Number во вложенных значениях на int64 или float64.
+func normalizeJSON(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return parseNumber(v.String())
+	case map[string]interface{}:
+		for key, item := range v {
+			normalized, err := normalizeJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = normalized
+		}
+		return v, nil
+	case []interface{}:
+		for i, item := range v {
+			normalized, err := normalizeJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalized
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// FormatValue возвращает текстовое представление значения в синтаксисе ParseValue.
+func FormatValue(value interface{}) string {
+	var buf bytes.Buffer
+	formatValue(&buf, value)
+	return buf.String()
+}
+
+func formatValue(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		buf.WriteString(formatFloat(v))
+	case string:
+		formatString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			formatValue(buf, item)
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			formatString(buf, key)
+			buf.WriteString(": ")
+			formatValue(buf, v[key])
+		}
+		buf.WriteByte('}')
+	default:
+		formatString(buf, fmt.Sprint(v))
+	}
+}
+
+// formatFloat выводит число так, чтобы при повторном разборе оно осталось float64.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "null"
+	}
+	text := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".eE") {
+		text += ".0"
+	}
+	return text
+}
+
+func formatString(buf *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	// Encode добавляет перевод строки в конце
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		text string
+		want interface{}
+		fail bool
+	}{
+		{text: "null", want: nil},
+		{text: "true", want: true},
+		{text: "false", want: false},
+		{text: "42", want: int64(42)},
+		{text: "-7", want: int64(-7)},
+		{text: "0", want: int64(0)},
+		{text: "9223372036854775807", want: int64(math.MaxInt64)},
+		{text: "9223372036854775808", want: 9223372036854775808.0},
+		{text: "3.14", want: 3.14},
+		{text: "2.0", want: 2.0},
+		{text: "1e-3", want: 0.001},
+		{text: "-1.5E3", want: -1500.0},
+		{text: "word", want: "word"},
+		{text: "01", want: "01"},
+		{text: `"with spaces"`, want: "with spaces"},
+		{text: `"esc\"aped\n"`, want: "esc\"aped\n"},
+		{text: `{"a": 1, "b": [2, 3.5, "x", null], "c": {"d": true}}`, want: map[string]interface{}{
+			"a": int64(1), "b": []interface{}{int64(2), 3.5, "x", nil}, "c": map[string]interface{}{"d": true}}},
+		{text: `[]`, want: []interface{}{}},
+		{text: `{"a":1} `, want: map[string]interface{}{"a": int64(1)}},
+
+		{text: `{"a":1}}`, fail: true},
+		{text: `{"a":1} {"b":2}`, fail: true},
+		{text: `[1]]`, fail: true},
+		{text: `[1] x`, fail: true},
+		{text: `"a" "b"`, fail: true},
+		{text: `"unclosed`, fail: true},
+		{text: `{"a":}`, fail: true},
+		{text: `{a:1}`, fail: true},
+	}
+	for _, test := range tests {
+		got, err := ParseValue(test.text)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: ожидалась ошибка, получено %#v", test.text, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: %#v, ожидалось %#v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestFormatValueRoundTrip(t *testing.T) {
+	values := []interface{}{
+		nil,
+		true,
+		false,
+		int64(0),
+		int64(-42),
+		int64(math.MaxInt64),
+		int64(math.MinInt64),
+		0.0,
+		2.0,
+		-0.5,
+		1e300,
+		1e-300,
+		"",
+		"word",
+		"null",
+		"42",
+		"two words",
+		"кириллица",
+		"<html> & \"quotes\" \\ \n\t",
+		[]interface{}{},
+		[]interface{}{int64(1), 2.5, "x", nil, []interface{}{true}},
+		map[string]interface{}{},
+		map[string]interface{}{"a": int64(1), "b c": map[string]interface{}{"d": []interface{}{1.0}}, "": nil},
+	}
+	for _, value := range values {
+		text := FormatValue(value)
+		got, err := ParseValue(text)
+		if err != nil {
+			t.Errorf("%#v: %s: %v", value, text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("%#v: %s разобрано как %#v", value, text, got)
+		}
+		if again := FormatValue(got); again != text {
+			t.Errorf("%#v: повторный вывод %s, ожидался %s", value, again, text)
+		}
+	}
+}
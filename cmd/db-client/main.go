@@ -0,0 +1,123 @@
+// Команда db-client отправляет команды серверу базы данных, запущенному
+// с флагом -listen, и выводит его ответы: по одному объекту JSON в строке.
+//
+// Команды читаются из аргументов командной строки (каждый аргумент — одна
+// команда) или, если аргументов нет, построчно со стандартного ввода.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"db/i18n"
+)
+
+// response ответ сервера на одну команду; line хранит его исходный текст.
+type response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	line  json.RawMessage
+}
+
+// client подключение к серверу.
+type client struct {
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+func dial(addr string, timeout time.Duration) (*client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &client{conn: conn, decoder: json.NewDecoder(conn)}, nil
+}
+
+// run отправляет команду и дожидается ответа.
+func (c *client) run(command string) (response, error) {
+	var resp response
+	if _, err := io.WriteString(c.conn, command+"\n"); err != nil {
+		return resp, err
+	}
+	if err := c.decoder.Decode(&resp.line); err != nil {
+		return resp, err
+	}
+	return resp, json.Unmarshal(resp.line, &resp)
+}
+
+func (c *client) close() error {
+	return c.conn.Close()
+}
+
+func main() {
+	// Справка по флагам выводится на языке из окружения; флаг -lang его переопределяет
+	if lang := i18n.EnvLanguage(); lang != "" {
+		i18n.SetLanguage(lang)
+	}
+	addr := flag.String("addr", "localhost:7070", i18n.Sprintf(i18n.FlagAddr))
+	timeout := flag.Duration("timeout", 5*time.Second, i18n.Sprintf(i18n.FlagTimeout))
+	lang := flag.String("lang", "", i18n.Sprintf(i18n.FlagLang))
+	flag.Parse()
+	if *lang != "" {
+		if err := i18n.SetLanguage(*lang); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	c, err := dial(*addr, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.Sprintf(i18n.ConnectError, err))
+		os.Exit(1)
+	}
+	defer c.close()
+
+	failed := false
+	execute := func(command string) bool {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			return true
+		}
+		resp, err := c.run(command)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.Sprintf(i18n.ServerIOError, err))
+			failed = true
+			return false
+		}
+		fmt.Printf("%s\n", resp.line)
+		if !resp.OK {
+			fmt.Fprintln(os.Stderr, i18n.Sprintf(i18n.CommandError, resp.Error))
+			failed = true
+		}
+		return command != "exit"
+	}
+
+	if flag.NArg() > 0 {
+		for _, command := range flag.Args() {
+			if !execute(command) {
+				break
+			}
+		}
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if !execute(scanner.Text()) {
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.Sprintf(i18n.InputError, err))
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
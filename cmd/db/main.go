@@ -0,0 +1,156 @@
+// Команда db запускает базу данных в консольном режиме или как сетевой сервер.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"db/engine"
+	"db/i18n"
+	"db/server"
+)
+
+func main() {
+	// Справка по флагам выводится на языке из окружения; флаг -lang его переопределяет
+	if lang := i18n.EnvLanguage(); lang != "" {
+		i18n.SetLanguage(lang)
+	}
+	dataDir := flag.String("data-dir", "", i18n.Sprintf(i18n.FlagDataDir))
+	listen := flag.String("listen", "", i18n.Sprintf(i18n.FlagListen))
+	httpAddr := flag.String("http", "", i18n.Sprintf(i18n.FlagHTTP))
+	format := flag.String("format", "text", i18n.Sprintf(i18n.FlagFormat))
+	lang := flag.String("lang", "", i18n.Sprintf(i18n.FlagLang))
+	flag.Parse()
+	if *lang != "" {
+		if err := i18n.SetLanguage(*lang); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+
+	formatter, err := engine.NewFormatter(*format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	// В формате json выводятся только результаты команд, без приглашений
+	prompt := func() {
+		if *format != "json" {
+			fmt.Println(i18n.Sprintf(i18n.EnterCommand))
+		}
+	}
+
+	db := engine.New()
+	if *dataDir != "" {
+		if db, err = engine.Open(*dataDir); err != nil {
+			fmt.Println(i18n.Sprintf(i18n.LoadError, err))
+			os.Exit(1)
+		}
+	}
+	defer db.Close()
+	if *listen != "" || *httpAddr != "" {
+		serve(db, *listen, *httpAddr)
+		if *dataDir != "" {
+			if err := db.Save(); err != nil {
+				fmt.Println(i18n.Sprintf(i18n.SaveError, err))
+			}
+		}
+		return
+	}
+	session := db.NewSession(os.Stdout)
+	session.SetFormatter(formatter)
+	scanner := bufio.NewScanner(os.Stdin)
+	prompt()
+	for scanner.Scan() {
+		command := scanner.Text()
+		if command == "exit" {
+			break
+		} else if strings.Contains(command, ".txt") {
+			file, err := os.Open(command)
+			if err != nil {
+				fmt.Println(i18n.Sprintf(i18n.FileOpenError, err))
+				continue
+			}
+			defer file.Close()
+
+			fileScanner := bufio.NewScanner(file)
+			for fileScanner.Scan() {
+				cmd := fileScanner.Text()
+				if err := engine.RunCommand(session, cmd); err != nil {
+					formatter.FormatError(os.Stdout, err)
+				}
+			}
+
+			if err := fileScanner.Err(); err != nil {
+				fmt.Println(i18n.Sprintf(i18n.FileReadError, err))
+			}
+		} else {
+			if err := engine.RunCommand(session, command); err != nil {
+				formatter.FormatError(os.Stdout, err)
+			}
+		}
+		prompt()
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println(i18n.Sprintf(i18n.InputError, err))
+	}
+	session.Close()
+	if *dataDir != "" {
+		if err := db.Save(); err != nil {
+			fmt.Println(i18n.Sprintf(i18n.SaveError, err))
+		}
+	}
+}
+
+// serve принимает подключения по TCP на адресе addr и запросы HTTP API на адресе
+// httpAddr (пустой адрес отключает соответствующий сервер), пока процесс не получит
+// сигнал завершения, после чего дожидается выполнения начатых команд.
+func serve(db *engine.Engine, addr, httpAddr string) {
+	errs := make(chan error, 2)
+	var tcpServer *server.Server
+	if addr != "" {
+		tcpServer = server.NewServer(db)
+		go func() {
+			errs <- tcpServer.ListenAndServe(addr)
+		}()
+		fmt.Println(i18n.Sprintf(i18n.ServerListening, addr))
+	}
+	var httpServer *http.Server
+	if httpAddr != "" {
+		httpServer = &http.Server{Addr: httpAddr, Handler: server.NewHTTPHandler(db)}
+		go func() {
+			if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+				errs <- err
+			}
+		}()
+		fmt.Println(i18n.Sprintf(i18n.HTTPListening, httpAddr))
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-errs:
+		fmt.Println(i18n.Sprintf(i18n.ServerError, err))
+	case <-signals:
+	}
+	fmt.Println(i18n.Sprintf(i18n.ServerStopping))
+	ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout)
+	defer cancel()
+	if tcpServer != nil {
+		if err := tcpServer.Shutdown(ctx); err != nil {
+			fmt.Println(i18n.Sprintf(i18n.ServerStopError, err))
+		}
+	}
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			fmt.Println(i18n.Sprintf(i18n.HTTPStopError, err))
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"sync"
+
+	"db/catalog"
+	"db/i18n"
+)
+
+// CommandHistory хранит выполненные и отмененные команды для undo/redo.
+// Сами команды выполняются без блокировки истории, поэтому команды над
+// разными коллекциями не ждут друг друга.
+type CommandHistory struct {
+	mu     sync.Mutex
+	done   []Command
+	undone []Command
+}
+
+func NewCommandHistory() *CommandHistory {
+	return &CommandHistory{}
+}
+
+// Execute выполняет команду и помещает ее в историю.
+// Новая команда делает невозможным повтор ранее отмененных.
+func (h *CommandHistory) Execute(cmd Command) error {
+	if err := cmd.Execute(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.done = append(h.done, cmd)
+	h.undone = nil
+	return nil
+}
+
+// Undo отменяет последнюю выполненную команду.
+func (h *CommandHistory) Undo() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.done) == 0 {
+		return i18n.Errorf(i18n.NothingToUndo)
+	}
+	cmd := h.done[len(h.done)-1]
+	if err := cmd.Undo(); err != nil {
+		return err
+	}
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, cmd)
+	return nil
+}
+
+// Redo повторно выполняет последнюю отмененную команду.
+func (h *CommandHistory) Redo() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.undone) == 0 {
+		return i18n.Errorf(i18n.NothingToRedo)
+	}
+	cmd := h.undone[len(h.undone)-1]
+	if err := cmd.Execute(); err != nil {
+		return err
+	}
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, cmd)
+	return nil
+}
+
+// next возвращает команду, которую отменит Undo (undo == true) или повторит Redo;
+// nil, если такой команды нет.
+func (h *CommandHistory) next(undo bool) Command {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	commands := h.undone
+	if undo {
+		commands = h.done
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	return commands[len(commands)-1]
+}
+
+// Clear очищает историю команд.
+func (h *CommandHistory) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.done = nil
+	h.undone = nil
+}
+
+// AddRecordCommand добавляет запись в коллекцию.
+type AddRecordCommand struct {
+	pool       *catalog.AllPools
+	poolName   string
+	schemaName string
+	collection string
+	key        string
+	value      interface{}
+}
+
+func (c *AddRecordCommand) Execute() error {
+	coll, err := c.pool.LookupCollection(c.poolName, c.schemaName, c.collection)
+	if err != nil {
+		return err
+	}
+	return coll.Insert(c.key, c.value)
+}
+
+func (c *AddRecordCommand) Undo() error {
+	coll, err := c.pool.LookupCollection(c.poolName, c.schemaName, c.collection)
+	if err != nil {
+		return err
+	}
+	return coll.Remove(c.key)
+}
+
+// DeleteRecordCommand удаляет запись, запоминая ее значение для отмены.
+type DeleteRecordCommand struct {
+	pool       *catalog.AllPools
+	poolName   string
+	schemaName string
+	collection string
+	key        string
+	previous   interface{}
+}
+
+func (c *DeleteRecordCommand) Execute() error {
+	coll, err := c.pool.LookupCollection(c.poolName, c.schemaName, c.collection)
+	if err != nil {
+		return err
+	}
+	previous, err := coll.Get(c.key)
+	if err != nil {
+		return err
+	}
+	if err := coll.Remove(c.key); err != nil {
+		return err
+	}
+	c.previous = previous
+	return nil
+}
+
+func (c *DeleteRecordCommand) Undo() error {
+	coll, err := c.pool.LookupCollection(c.poolName, c.schemaName, c.collection)
+	if err != nil {
+		return err
+	}
+	return coll.Insert(c.key, c.previous)
+}
+
+// RemoveCollectionCommand удаляет коллекцию из схемы; при отмене коллекция
+// возвращается вместе с записями и историей изменений.
+type RemoveCollectionCommand struct {
+	pool       *catalog.AllPools
+	poolName   string
+	schemaName string
+	collection string
+	removed    *catalog.VersionedCollection
+}
+
+func (c *RemoveCollectionCommand) Execute() error {
+	pool, err := c.pool.GetPool(c.poolName)
+	if err != nil {
+		return err
+	}
+	schema, err := pool.GetSchema(c.schemaName)
+	if err != nil {
+		return err
+	}
+	removed, err := schema.GetVersionedCollection(c.collection)
+	if err != nil {
+		return err
+	}
+	if err := schema.RemoveCollection(c.collection); err != nil {
+		return err
+	}
+	c.removed = removed
+	return nil
+}
+
+func (c *RemoveCollectionCommand) Undo() error {
+	pool, err := c.pool.GetPool(c.poolName)
+	if err != nil {
+		return err
+	}
+	schema, err := pool.GetSchema(c.schemaName)
+	if err != nil {
+		return err
+	}
+	return schema.AttachCollection(c.collection, c.removed)
+}
+
+// RemoveSchemaCommand удаляет схему из пула, сохраняя ее копию для отмены.
+type RemoveSchemaCommand struct {
+	pool       *catalog.AllPools
+	poolName   string
+	schemaName string
+	removed    *catalog.Schema
+}
+
+func (c *RemoveSchemaCommand) Execute() error {
+	pool, err := c.pool.GetPool(c.poolName)
+	if err != nil {
+		return err
+	}
+	schema, err := pool.GetSchema(c.schemaName)
+	if err != nil {
+		return err
+	}
+	// RemoveSchema очищает схему, поэтому для отмены сохраняется ее копия
+	c.removed = schema.Clone()
+	return pool.RemoveSchema(c.schemaName)
+}
+
+func (c *RemoveSchemaCommand) Undo() error {
+	pool, err := c.pool.GetPool(c.poolName)
+	if err != nil {
+		return err
+	}
+	return pool.AttachSchema(c.schemaName, c.removed.Clone())
+}
+
+// RemovePoolCommand удаляет пул, сохраняя его копию для отмены.
+type RemovePoolCommand struct {
+	pool     *catalog.AllPools
+	poolName string
+	removed  *catalog.Pool
+}
+
+func (c *RemovePoolCommand) Execute() error {
+	pool, err := c.pool.GetPool(c.poolName)
+	if err != nil {
+		return err
+	}
+	c.removed = pool.Clone()
+	return c.pool.RemovePool(c.poolName)
+}
+
+func (c *RemovePoolCommand) Undo() error {
+	return c.pool.AttachPool(c.poolName, c.removed.Clone())
+}
+
+// Команда, изменяющая данные. Undo отменяет результат Execute.
+type Command interface {
+	Execute() error
+	Undo() error
+}
+
+// SaveCommand изменяет значение записи по адресу пул/схема/коллекция/ключ.
+// Предыдущее значение запоминается для отмены и попадает в историю коллекции
+// вместе с новой версией.
+type SaveCommand struct {
+	pool       *catalog.AllPools
+	poolName   string
+	schemaName string
+	collection string
+	key        string
+	value      interface{}
+	previous   interface{}
+}
+
+func (c *SaveCommand) Execute() error {
+	coll, err := c.pool.LookupCollection(c.poolName, c.schemaName, c.collection)
+	if err != nil {
+		return err
+	}
+	previous, err := coll.Get(c.key)
+	if err != nil {
+		return err
+	}
+	if err := coll.Update(c.key, c.value); err != nil {
+		return err
+	}
+	c.previous = previous
+	return nil
+}
+
+func (c *SaveCommand) Undo() error {
+	coll, err := c.pool.LookupCollection(c.poolName, c.schemaName, c.collection)
+	if err != nil {
+		return err
+	}
+	return coll.Update(c.key, c.previous)
+}
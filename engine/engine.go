@@ -0,0 +1,123 @@
+// Package engine выполняет команды над каталогом данных: разбирает текст команд,
+// ведет сеансы клиентов с историей undo/redo и транзакциями, записывает изменения
+// в журнал упреждающей записи и сохраняет контрольные точки.
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"db/catalog"
+	"db/i18n"
+)
+
+// Engine база данных: общие для всех сеансов пулы, журнал и контрольные точки.
+type Engine struct {
+	pools   *catalog.AllPools
+	dataDir string // каталог данных; пустой, если пулы хранятся только в памяти
+	wal     *WAL   // журнал упреждающей записи; nil без каталога данных
+	// sessions открытые сеансы по номерам; защищены sessionsMu
+	sessionsMu  sync.Mutex
+	sessions    map[uint64]*Session
+	lastSession uint64
+	// checkpoint удерживается изменяющими командами на чтение, а Save — на запись,
+	// чтобы в контрольную точку не попадали изменения, еще не записанные в журнал
+	checkpoint sync.RWMutex
+	// order упорядочивает запись в журнал и применение команд, затрагивающих
+	// одни и те же пулы, схемы и коллекции (см. order.go)
+	order *orderLocks
+}
+
+// New создает базу данных, хранящую пулы только в памяти.
+func New() *Engine {
+	return newEngine(catalog.InitPool())
+}
+
+func newEngine(pools *catalog.AllPools) *Engine {
+	return &Engine{
+		pools:    pools,
+		sessions: make(map[uint64]*Session),
+		order:    newOrderLocks(),
+	}
+}
+
+// Open открывает базу данных в каталоге dataDir: загружает последнюю контрольную
+// точку, повторяет поверх нее команды из журнала упреждающей записи и открывает
+// журнал для новых команд.
+func Open(dataDir string) (*Engine, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	pools, checkpointLSN, err := catalog.LoadCheckpoint(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	e := newEngine(pools)
+	walPath := filepath.Join(dataDir, walFileName)
+	entries, size, err := readWAL(walPath)
+	if err != nil {
+		return nil, err
+	}
+	lsn, err := e.replayWAL(entries, checkpointLSN)
+	if err != nil {
+		return nil, err
+	}
+	if e.wal, err = OpenWAL(walPath, lsn, size); err != nil {
+		return nil, err
+	}
+	e.dataDir = dataDir
+	if lsn != checkpointLSN {
+		if err := e.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Pools возвращает пулы базы данных.
+func (e *Engine) Pools() *catalog.AllPools {
+	return e.pools
+}
+
+// DataDir возвращает каталог данных или пустую строку для базы в памяти.
+func (e *Engine) DataDir() string {
+	return e.dataDir
+}
+
+// Save сохраняет все пулы в каталог данных (контрольная точка) и очищает журнал.
+// Команды, выполненные до контрольной точки, после нее отменить нельзя: журнал
+// повторяется поверх контрольной точки с пустой историей команд.
+func (e *Engine) Save() error {
+	if e.dataDir == "" {
+		return i18n.Errorf(i18n.DataDirNotSet)
+	}
+	e.checkpoint.Lock()
+	defer e.checkpoint.Unlock()
+	if err := e.checkNoTransactions(); err != nil {
+		return err
+	}
+	var lsn uint64
+	if e.wal != nil {
+		lsn = e.wal.LSN()
+	}
+	if err := e.pools.WriteCheckpoint(e.dataDir, lsn); err != nil {
+		return err
+	}
+	e.clearCommandHistories()
+	if e.wal != nil {
+		if err := e.wal.Truncate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close закрывает журнал. Несохраненные изменения остаются в журнале
+// и применяются при следующем открытии.
+func (e *Engine) Close() error {
+	if e.wal == nil {
+		return nil
+	}
+	return e.wal.Close()
+}
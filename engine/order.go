@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"db/catalog"
+)
+
+// Порядок изменяющих команд. Команда записывается в журнал до применения, и при
+// повторе журнала команды выполняются в порядке записей. Поэтому команды, которые
+// могут повлиять друг на друга, должны записываться и применяться в одном порядке,
+// а остальные не обязаны ждать друг друга. Для этого команда до записи в журнал
+// блокирует имена, которые затрагивает: коллекцию, которую изменяет, — на запись,
+// а содержащие ее схему и пул — на чтение, чтобы они не были удалены или
+// созданы заново, пока команда выполняется.
+
+// orderName путь к пулу, схеме или коллекции, записанный одной строкой.
+type orderName string
+
+// orderNameOf возвращает имя для пути из имен пула, схемы и коллекции. Нулевой
+// байт меньше любого символа имени, поэтому имена упорядочены как пути.
+func orderNameOf(path ...string) orderName {
+	return orderName(strings.Join(path, "\x00"))
+}
+
+// orderScope имена, которые команда блокирует на запись (true) или на чтение.
+type orderScope map[orderName]bool
+
+// write добавляет в область путь path на запись, а содержащие его имена на чтение.
+func (s orderScope) write(path ...string) {
+	s.read(path[:len(path)-1]...)
+	s[orderNameOf(path...)] = true
+}
+
+// read добавляет в область путь path и содержащие его имена на чтение; имена,
+// уже добавленные на запись, остаются на запись.
+func (s orderScope) read(path ...string) {
+	for i := 1; i <= len(path); i++ {
+		name := orderNameOf(path[:i]...)
+		if _, exists := s[name]; !exists {
+			s[name] = false
+		}
+	}
+}
+
+// commandScope возвращает имена, которые затрагивает изменяющая команда args
+// сеанса session. Команды, меняющие только состояние сеанса (операции внутри
+// транзакции, rollback), не блокируют ничего: их порядок задан самим сеансом.
+func commandScope(session *Session, args []string) orderScope {
+	scope := make(orderScope)
+	switch args[0] {
+	case "add-pool", "remove-pool", "begin":
+		if len(args) < 2 {
+			break
+		}
+		if args[0] == "begin" {
+			scope.read(args[1])
+		} else {
+			scope.write(args[1])
+		}
+	case "add-schema", "remove-schema":
+		if len(args) >= 3 {
+			scope.write(args[1], args[2])
+		}
+	case "add-collection", "remove-collection", "create-index":
+		if len(args) >= 4 {
+			scope.write(args[1], args[2], args[3])
+		}
+	case "add-record", "update-record", "delete-record":
+		if session.tx == nil && len(args) >= 4 {
+			scope.write(args[1], args[2], args[3])
+		}
+	case "commit":
+		if session.tx != nil {
+			transactionScope(scope, session.tx)
+		}
+	case "undo", "redo":
+		if cmd := session.commands.next(args[0] == "undo"); cmd != nil {
+			historyScope(scope, cmd)
+		}
+	}
+	return scope
+}
+
+// historyScope добавляет в область имена, которые затрагивает отмена или повтор cmd.
+func historyScope(scope orderScope, cmd Command) {
+	switch c := cmd.(type) {
+	case *AddRecordCommand:
+		scope.write(c.poolName, c.schemaName, c.collection)
+	case *DeleteRecordCommand:
+		scope.write(c.poolName, c.schemaName, c.collection)
+	case *SaveCommand:
+		scope.write(c.poolName, c.schemaName, c.collection)
+	case *RemoveCollectionCommand:
+		scope.write(c.poolName, c.schemaName, c.collection)
+	case *RemoveSchemaCommand:
+		scope.write(c.poolName, c.schemaName)
+	case *RemovePoolCommand:
+		scope.write(c.poolName)
+	case *catalog.Transaction:
+		transactionScope(scope, c)
+	}
+}
+
+// transactionScope добавляет в область коллекции транзакции tx.
+func transactionScope(scope orderScope, tx *catalog.Transaction) {
+	scope.read(tx.Pool())
+	for _, name := range tx.Collections() {
+		scope.write(tx.Pool(), name.Schema, name.Collection)
+	}
+}
+
+// orderLocks блокировки имен. Блокировка существует, пока ее кто-то удерживает
+// или ждет.
+type orderLocks struct {
+	mu    sync.Mutex
+	locks map[orderName]*orderLock
+}
+
+type orderLock struct {
+	sync.RWMutex
+	refs int // число команд, удерживающих или ждущих блокировку; защищено orderLocks.mu
+}
+
+func newOrderLocks() *orderLocks {
+	return &orderLocks{locks: make(map[orderName]*orderLock)}
+}
+
+// acquire блокирует имена области в порядке имен, чтобы команды с пересекающимися
+// областями не ждали друг друга по кругу, и возвращает функцию снятия блокировок.
+func (l *orderLocks) acquire(scope orderScope) func() {
+	names := make([]orderName, 0, len(scope))
+	for name := range scope {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	locks := make([]*orderLock, len(names))
+	l.mu.Lock()
+	for i, name := range names {
+		lock, exists := l.locks[name]
+		if !exists {
+			lock = &orderLock{}
+			l.locks[name] = lock
+		}
+		lock.refs++
+		locks[i] = lock
+	}
+	l.mu.Unlock()
+
+	for i, name := range names {
+		if scope[name] {
+			locks[i].Lock()
+		} else {
+			locks[i].RLock()
+		}
+	}
+	return func() {
+		for i := len(names) - 1; i >= 0; i-- {
+			if scope[names[i]] {
+				locks[i].Unlock()
+			} else {
+				locks[i].RUnlock()
+			}
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, name := range names {
+			if locks[i].refs--; locks[i].refs == 0 {
+				delete(l.locks, name)
+			}
+		}
+	}
+}
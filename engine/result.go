@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"db/catalog"
+	"db/i18n"
+	"db/tree"
+)
+
+// ResultKind вид результата команды; определяет, какие поля Result заполнены.
+type ResultKind string
+
+const (
+	ResultMessage   ResultKind = "message"   // только сообщение о выполненном действии
+	ResultRecord    ResultKind = "record"    // одна запись в Records
+	ResultRecords   ResultKind = "records"   // список записей в Records и ключ продолжения Next
+	ResultHistory   ResultKind = "history"   // версии записи Key в Versions
+	ResultAggregate ResultKind = "aggregate" // значения функции Function по полю Field в Groups
+)
+
+// Result результат выполнения одной команды.
+type Result struct {
+	Kind     ResultKind
+	Message  i18n.Message
+	Records  []tree.Record
+	Next     string
+	Key      string
+	Versions []catalog.Version
+	Function string
+	Field    string
+	GroupBy  string
+	Groups   []catalog.AggregateGroup
+}
+
+func messageResult(id i18n.ID, args ...interface{}) *Result {
+	return &Result{Kind: ResultMessage, Message: i18n.NewMessage(id, args...)}
+}
+
+func recordsResult(records []tree.Record) *Result {
+	return &Result{Kind: ResultRecords, Records: records}
+}
+
+// Коды ошибок в машиночитаемом выводе.
+const (
+	CodePoolNotFound       = "pool_not_found"
+	CodeSchemaNotFound     = "schema_not_found"
+	CodeCollectionNotFound = "collection_not_found"
+	CodeKeyNotFound        = "key_not_found"
+	CodePoolExists         = "pool_exists"
+	CodeSchemaExists       = "schema_exists"
+	CodeCollectionExists   = "collection_exists"
+	CodeDuplicateKey       = "duplicate_key"
+	CodeError              = "error"
+)
+
+// ErrorCode возвращает код ошибки err; ошибки без отдельного кода получают CodeError.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, catalog.ErrPoolNotFound):
+		return CodePoolNotFound
+	case errors.Is(err, catalog.ErrSchemaNotFound):
+		return CodeSchemaNotFound
+	case errors.Is(err, catalog.ErrCollectionNotFound):
+		return CodeCollectionNotFound
+	case errors.Is(err, catalog.ErrKeyNotFound):
+		return CodeKeyNotFound
+	case errors.Is(err, catalog.ErrPoolExists):
+		return CodePoolExists
+	case errors.Is(err, catalog.ErrSchemaExists):
+		return CodeSchemaExists
+	case errors.Is(err, catalog.ErrCollectionExists):
+		return CodeCollectionExists
+	case errors.Is(err, catalog.ErrDuplicateKey):
+		return CodeDuplicateKey
+	default:
+		return CodeError
+	}
+}
+
+// Formatter выводит результаты и ошибки команд.
+type Formatter interface {
+	FormatResult(out io.Writer, result *Result) error
+	FormatError(out io.Writer, err error) error
+}
+
+// NewFormatter возвращает формат вывода по имени: text — текст для человека,
+// json — по одному JSON-объекту в строке на команду.
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case "", "text":
+		return TextFormatter{}, nil
+	case "json":
+		return JSONFormatter{}, nil
+	default:
+		return nil, i18n.Errorf(i18n.UnknownFormat, format)
+	}
+}
+
+// TextFormatter выводит результаты в виде текста.
+type TextFormatter struct{}
+
+func (TextFormatter) FormatResult(out io.Writer, result *Result) error {
+	var err error
+	print := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(out, format, args...)
+		}
+	}
+	switch result.Kind {
+	case ResultRecord:
+		for _, record := range result.Records {
+			print("key: %v, value: %v\n", record.Key, catalog.FormatValue(record.Value))
+		}
+	case ResultRecords:
+		for _, record := range result.Records {
+			print("key: %v, value: %v\n", record.Key, catalog.FormatValue(record.Value))
+		}
+		print("%s\n", i18n.Sprintf(i18n.TotalRecords, len(result.Records)))
+		if result.Next != "" {
+			print("%s\n", i18n.Sprintf(i18n.Continuation, result.Next))
+		}
+	case ResultHistory:
+		exists := false
+		for _, version := range result.Versions {
+			t := version.Time.Format(time.RFC3339Nano)
+			if version.Deleted {
+				print("%s\n", i18n.Sprintf(i18n.VersionDeleted, t, catalog.FormatValue(version.Previous)))
+			} else if exists {
+				print("%s\n", i18n.Sprintf(i18n.VersionReplaced, t, catalog.FormatValue(version.Value), catalog.FormatValue(version.Previous)))
+			} else {
+				print("%s: %v\n", t, catalog.FormatValue(version.Value))
+			}
+			exists = !version.Deleted
+		}
+	case ResultAggregate:
+		for _, group := range result.Groups {
+			if result.GroupBy != "" {
+				print("%s: %v, ", result.GroupBy, catalog.FormatValue(group.Group))
+			}
+			print("%s(%s): %v\n", result.Function, result.Field, catalog.FormatValue(group.Value))
+		}
+	}
+	if result.Message.ID != "" {
+		print("%s\n", result.Message)
+	}
+	return err
+}
+
+func (TextFormatter) FormatError(out io.Writer, err error) error {
+	_, werr := fmt.Fprintln(out, i18n.Sprintf(i18n.CommandError, err))
+	return werr
+}
+
+// JSONFormatter выводит каждый результат одной строкой JSON:
+//
+//	{"ok":true,"kind":"records","records":[{"key":"a","value":1}],"count":1}
+//	{"ok":false,"code":"key_not_found","error":"Элемент не найден!"}
+//
+// Значения записей передаются как JSON в синтаксисе значений команд. Поле message
+// содержит текст на выбранном языке, message_id — идентификатор сообщения из i18n.
+type JSONFormatter struct{}
+
+type jsonResult struct {
+	OK        bool          `json:"ok"`
+	Kind      ResultKind    `json:"kind,omitempty"`
+	Message   string        `json:"message,omitempty"`
+	MessageID i18n.ID       `json:"message_id,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Record    *jsonRecord   `json:"record,omitempty"`
+	Records   *[]jsonRecord `json:"records,omitempty"`
+	Count     *int          `json:"count,omitempty"`
+	Next      string        `json:"next,omitempty"`
+	Key       string        `json:"key,omitempty"`
+	Versions  []jsonVersion `json:"versions,omitempty"`
+	Function  string        `json:"function,omitempty"`
+	Field     string        `json:"field,omitempty"`
+	GroupBy   string        `json:"group_by,omitempty"`
+	Groups    []jsonGroup   `json:"groups,omitempty"`
+}
+
+type jsonRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonVersion struct {
+	Time     time.Time       `json:"time"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	Previous json.RawMessage `json:"previous,omitempty"`
+	Deleted  bool            `json:"deleted,omitempty"`
+}
+
+type jsonGroup struct {
+	Group json.RawMessage `json:"group,omitempty"`
+	Count int64           `json:"count"`
+	Value json.RawMessage `json:"value"`
+}
+
+func jsonValue(value interface{}) json.RawMessage {
+	return json.RawMessage(catalog.FormatValue(value))
+}
+
+func (JSONFormatter) FormatResult(out io.Writer, result *Result) error {
+	encoded := jsonResult{
+		OK:        true,
+		Kind:      result.Kind,
+		Message:   result.Message.String(),
+		MessageID: result.Message.ID,
+		Next:      result.Next,
+		Key:       result.Key,
+		Function:  result.Function,
+		Field:     result.Field,
+		GroupBy:   result.GroupBy,
+	}
+	records := make([]jsonRecord, 0, len(result.Records))
+	for _, record := range result.Records {
+		records = append(records, jsonRecord{Key: record.Key, Value: jsonValue(record.Value)})
+	}
+	switch result.Kind {
+	case ResultRecord:
+		if len(records) > 0 {
+			encoded.Record = &records[0]
+		}
+	case ResultRecords:
+		count := len(records)
+		encoded.Records, encoded.Count = &records, &count
+	case ResultHistory:
+		for _, version := range result.Versions {
+			stored := jsonVersion{Time: version.Time, Deleted: version.Deleted}
+			if !version.Deleted {
+				stored.Value = jsonValue(version.Value)
+			}
+			if version.Previous != nil {
+				stored.Previous = jsonValue(version.Previous)
+			}
+			encoded.Versions = append(encoded.Versions, stored)
+		}
+	case ResultAggregate:
+		for _, group := range result.Groups {
+			stored := jsonGroup{Count: group.Count, Value: jsonValue(group.Value)}
+			if result.GroupBy != "" {
+				stored.Group = jsonValue(group.Group)
+			}
+			encoded.Groups = append(encoded.Groups, stored)
+		}
+	}
+	return writeJSONLine(out, encoded)
+}
+
+func (JSONFormatter) FormatError(out io.Writer, err error) error {
+	encoded := jsonResult{OK: false, Code: ErrorCode(err), Error: err.Error()}
+	var message *i18n.Error
+	if errors.As(err, &message) {
+		encoded.MessageID = message.ID
+	}
+	return writeJSONLine(out, encoded)
+}
+
+func writeJSONLine(out io.Writer, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,536 @@
+package engine
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"db/catalog"
+	"db/i18n"
+	"db/tree"
+)
+
+// RunCommand выполняет команду в сеансе session и выводит результат в его поток
+// в формате сеанса. Ошибка команды возвращается вызывающему и не выводится.
+func RunCommand(session *Session, command string) error {
+	result, err := Execute(session, command)
+	if err != nil {
+		return err
+	}
+	return session.formatter.FormatResult(session.out, result)
+}
+
+// Execute выполняет команду в сеансе session и возвращает ее результат.
+func Execute(session *Session, command string) (*Result, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, i18n.Errorf(i18n.NoCommand)
+	}
+	if session.tx != nil && mutatingCommands[args[0]] && !transactionalCommands[args[0]] {
+		return nil, i18n.Errorf(i18n.CommandInTransaction, args[0])
+	}
+	if !mutatingCommands[args[0]] {
+		return execute(session, command, args)
+	}
+
+	e := session.engine
+	if e.wal == nil {
+		// Без журнала порядок изменений задают блокировки каталога
+		return execute(session, command, args)
+	}
+	e.checkpoint.RLock()
+	defer e.checkpoint.RUnlock()
+	// Команда записывается в журнал до применения. Команды, затрагивающие одни
+	// и те же имена, записываются и применяются по одной, остальные — параллельно
+	release := e.order.acquire(commandScope(session, args))
+	defer release()
+	lsn, err := e.wal.Append(session.id, command, session.pools.Now())
+	if err != nil {
+		return nil, i18n.Wrap(err, i18n.WALWrite, err)
+	}
+	result, err := execute(session, command, args)
+	if err != nil {
+		// Повтор журнала выполнит команду снова и должен получить ту же ошибку
+		if werr := e.wal.AppendFailure(session.id, lsn); werr != nil {
+			return nil, i18n.Wrap(werr, i18n.WALWrite, werr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// execute выполняет команду command, разобранную на аргументы args, без записи в журнал.
+func execute(session *Session, command string, args []string) (*Result, error) {
+	pools := session.pools
+	switch args[0] {
+	case "add-pool":
+		if len(args) < 2 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "add-pool")
+		}
+		if err := pools.AddPool(args[1]); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.PoolAdded, args[1]), nil
+	case "remove-pool":
+		if len(args) < 2 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "remove-pool")
+		}
+		cmd := &RemovePoolCommand{pool: pools, poolName: args[1]}
+		if err := session.commands.Execute(cmd); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.PoolRemoved, args[1]), nil
+	case "add-schema":
+		if len(args) < 3 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "add-schema")
+		}
+		pool, err := pools.GetPool(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := pool.AddSchema(args[2]); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.SchemaAdded, args[2], args[1]), nil
+	case "remove-schema":
+		if len(args) < 3 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "remove-schema")
+		}
+		cmd := &RemoveSchemaCommand{pool: pools, poolName: args[1], schemaName: args[2]}
+		if err := session.commands.Execute(cmd); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.SchemaRemoved, args[2], args[1]), nil
+	case "add-collection":
+		if len(args) < 4 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "add-collection")
+		}
+		pool, err := pools.GetPool(args[1])
+		if err != nil {
+			return nil, err
+		}
+		schema, err := pool.GetSchema(args[2])
+		if err != nil {
+			return nil, err
+		}
+		// Необязательные аргументы: тип коллекции, степень B-дерева и описание полей
+		options := args[4:]
+		var fields *catalog.RecordSchema
+		if len(options) > 0 && strings.HasPrefix(options[len(options)-1], "[") {
+			if fields, err = catalog.ParseRecordSchema(options[len(options)-1]); err != nil {
+				return nil, err
+			}
+			options = options[:len(options)-1]
+		}
+		collectionType := ""
+		if len(options) > 0 {
+			collectionType = options[0]
+		}
+		var collection tree.Collection
+		if collectionType == "btree" && len(options) > 1 {
+			t, err := strconv.Atoi(options[1])
+			if err != nil {
+				return nil, i18n.Errorf(i18n.InvalidBTreeDegree, options[1])
+			}
+			if collection, err = tree.NewBTreeCollection(t); err != nil {
+				return nil, err
+			}
+		} else if collection, err = tree.NewCollection(collectionType); err != nil {
+			return nil, err
+		}
+		versioned := catalog.NewVersionedCollection(collection)
+		versioned.SetRecordSchema(fields)
+		if err = schema.AddCollection(args[3], versioned); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.CollectionAdded, args[3], args[2], args[1]), nil
+	case "remove-collection":
+		if len(args) < 4 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "remove-collection")
+		}
+		cmd := &RemoveCollectionCommand{pool: pools, poolName: args[1], schemaName: args[2], collection: args[3]}
+		if err := session.commands.Execute(cmd); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.CollectionRemoved, args[3], args[2], args[1]), nil
+	case "add-record":
+		if len(args) < 6 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "add-record")
+		}
+		value, err := catalog.ParseValue(args[5])
+		if err != nil {
+			return nil, err
+		}
+		if session.tx != nil {
+			return bufferInTransaction(session.tx, args[1], func() error {
+				return session.tx.Insert(args[2], args[3], args[4], value)
+			})
+		}
+		cmd := &AddRecordCommand{
+			pool:       pools,
+			poolName:   args[1],
+			schemaName: args[2],
+			collection: args[3],
+			key:        args[4],
+			value:      value,
+		}
+		if err := session.commands.Execute(cmd); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.RecordAdded, args[4]), nil
+	case "update-record":
+		if len(args) < 6 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "update-record")
+		}
+		value, err := catalog.ParseValue(args[5])
+		if err != nil {
+			return nil, err
+		}
+		if session.tx != nil {
+			return bufferInTransaction(session.tx, args[1], func() error {
+				return session.tx.Update(args[2], args[3], args[4], value)
+			})
+		}
+		cmd := &SaveCommand{
+			pool:       pools,
+			poolName:   args[1],
+			schemaName: args[2],
+			collection: args[3],
+			key:        args[4],
+			value:      value,
+		}
+		if err := session.commands.Execute(cmd); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.RecordUpdated, args[4]), nil
+	case "read-record":
+		if len(args) < 5 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "read-record")
+		}
+		pool, err := pools.GetPool(args[1])
+		if err != nil {
+			return nil, err
+		}
+		schema, err := pool.GetSchema(args[2])
+		if err != nil {
+			return nil, err
+		}
+		collection, err := schema.GetVersionedCollection(args[3])
+		if err != nil {
+			return nil, err
+		}
+		var result interface{}
+		if session.tx != nil && session.tx.Pool() == args[1] {
+			// Внутри транзакции видны ее незафиксированные изменения
+			result, err = session.tx.Get(args[2], args[3], args[4])
+		} else {
+			result, err = pools.Snapshot().View(collection).Get(args[4])
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: ResultRecord, Records: []tree.Record{{Key: args[4], Value: result}}}, nil
+	case "read-history":
+		if len(args) < 5 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "read-history")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		versions := collection.History().Versions(args[4])
+		if len(versions) == 0 {
+			return nil, catalog.ErrKeyNotFound
+		}
+		return &Result{Kind: ResultHistory, Key: args[4], Versions: versions}, nil
+	case "read-range":
+		if len(args) < 6 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "read-range")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		records, err := pools.Snapshot().View(collection).GetRangeRecords(args[4], args[5])
+		if err != nil {
+			return nil, err
+		}
+		return recordsResult(records), nil
+	case "create-index":
+		if len(args) < 5 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "create-index")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		indexType := ""
+		if len(args) > 5 {
+			indexType = args[5]
+		}
+		if err := collection.CreateIndex(args[4], indexType); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.IndexCreated, args[4], args[3]), nil
+	case "read-by-field":
+		if len(args) < 6 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "read-by-field")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		value, err := catalog.ParseValue(args[5])
+		if err != nil {
+			return nil, err
+		}
+		records, err := collection.FindByField(args[4], value)
+		if err != nil {
+			return nil, err
+		}
+		return recordsResult(records), nil
+	case "read-range-by-field":
+		if len(args) < 7 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "read-range-by-field")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		minValue, err := catalog.ParseValue(args[5])
+		if err != nil {
+			return nil, err
+		}
+		maxValue, err := catalog.ParseValue(args[6])
+		if err != nil {
+			return nil, err
+		}
+		records, err := collection.FindRangeByField(args[4], minValue, maxValue)
+		if err != nil {
+			return nil, err
+		}
+		return recordsResult(records), nil
+	case "select":
+		records, err := pools.Query(command)
+		if err != nil {
+			return nil, err
+		}
+		return recordsResult(records), nil
+	case "aggregate":
+		if len(args) < 6 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "aggregate")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		groupBy := ""
+		if len(args) > 6 {
+			if args[6] != "group-by" || len(args) < 8 {
+				return nil, i18n.Errorf(i18n.GroupByExpected)
+			}
+			groupBy = args[7]
+		}
+		groups, err := catalog.Aggregate(pools.Snapshot().View(collection), args[4], args[5], groupBy)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: ResultAggregate, Function: args[4], Field: args[5], GroupBy: groupBy, Groups: groups}, nil
+	case "scan-prefix":
+		if len(args) < 5 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "scan-prefix")
+		}
+		collection, err := pools.LookupCollection(args[1], args[2], args[3])
+		if err != nil {
+			return nil, err
+		}
+		limit := catalog.DefaultPageSize
+		if len(args) > 5 {
+			if limit, err = strconv.Atoi(args[5]); err != nil || limit <= 0 {
+				return nil, i18n.Errorf(i18n.InvalidPageSize, args[5])
+			}
+		}
+		after := ""
+		if len(args) > 6 {
+			after = args[6]
+		}
+		records, next := catalog.ScanPrefix(pools.Snapshot().View(collection), args[4], limit, after)
+		return &Result{Kind: ResultRecords, Records: records, Next: next}, nil
+	case "read-record-at":
+		if len(args) < 6 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "read-record-at")
+		}
+		t, err := parseTime(args[5])
+		if err != nil {
+			return nil, err
+		}
+		result, err := pools.GetRecordAt(args[1], args[2], args[3], args[4], t)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: ResultRecord, Records: []tree.Record{{Key: args[4], Value: result}}}, nil
+	case "dump-collection-at":
+		if len(args) < 5 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "dump-collection-at")
+		}
+		t, err := parseTime(args[4])
+		if err != nil {
+			return nil, err
+		}
+		snapshot, err := pools.GetCollection(args[1], args[2], args[3], t)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(snapshot))
+		for key := range snapshot {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		records := make([]tree.Record, 0, len(keys))
+		for _, key := range keys {
+			records = append(records, tree.Record{Key: key, Value: snapshot[key]})
+		}
+		return recordsResult(records), nil
+	case "delete-record":
+		if len(args) < 5 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "delete-record")
+		}
+		if session.tx != nil {
+			return bufferInTransaction(session.tx, args[1], func() error {
+				return session.tx.Remove(args[2], args[3], args[4])
+			})
+		}
+		cmd := &DeleteRecordCommand{
+			pool:       pools,
+			poolName:   args[1],
+			schemaName: args[2],
+			collection: args[3],
+			key:        args[4],
+		}
+		if err := session.commands.Execute(cmd); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.RecordDeleted, args[4]), nil
+	case "undo":
+		if err := session.commands.Undo(); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.CommandUndone), nil
+	case "redo":
+		if err := session.commands.Redo(); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.CommandRedone), nil
+	case "begin":
+		if len(args) < 2 {
+			return nil, i18n.Errorf(i18n.NotEnoughArguments, "begin")
+		}
+		if session.tx != nil {
+			return nil, i18n.Errorf(i18n.TransactionAlreadyStarted)
+		}
+		tx, err := pools.Begin(args[1])
+		if err != nil {
+			return nil, err
+		}
+		session.tx = tx
+		return messageResult(i18n.TransactionStarted, args[1]), nil
+	case "commit":
+		if session.tx == nil {
+			return nil, i18n.Errorf(i18n.NoActiveTransaction)
+		}
+		tx := session.tx
+		session.tx = nil
+		// Зафиксированная транзакция отменяется командой undo как одна команда
+		if err := session.commands.Execute(tx); err != nil {
+			return nil, i18n.Wrap(err, i18n.TransactionAborted, err)
+		}
+		return messageResult(i18n.TransactionCommitted), nil
+	case "rollback":
+		if session.tx == nil {
+			return nil, i18n.Errorf(i18n.NoActiveTransaction)
+		}
+		session.tx.Rollback()
+		session.tx = nil
+		return messageResult(i18n.TransactionRolledBack), nil
+	case "save":
+		if session.tx != nil {
+			return nil, i18n.Errorf(i18n.CommandInTransaction, "save")
+		}
+		if err := session.engine.Save(); err != nil {
+			return nil, err
+		}
+		return messageResult(i18n.DataSaved, session.engine.dataDir), nil
+	case "exit":
+		return &Result{Kind: ResultMessage}, nil
+	default:
+		return nil, i18n.Errorf(i18n.UnknownCommand)
+	}
+}
+
+// bufferInTransaction добавляет операцию над пулом poolName в активную транзакцию.
+func bufferInTransaction(tx *catalog.Transaction, poolName string, add func() error) (*Result, error) {
+	if poolName != tx.Pool() {
+		return nil, i18n.Errorf(i18n.TransactionOtherPool, tx.Pool(), poolName)
+	}
+	if err := add(); err != nil {
+		return nil, err
+	}
+	return messageResult(i18n.OperationBuffered), nil
+}
+
+// parseTime разбирает время в формате RFC3339.
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, i18n.Errorf(i18n.InvalidTime, value)
+	}
+	return t, nil
+}
+
+// splitCommand разбивает команду на аргументы по пробельным символам, не разделяя
+// строки в кавычках и содержимое фигурных и квадратных скобок.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inToken, inString, escaped := false, false, false
+	depth := 0
+	for _, r := range command {
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == '"' {
+				inString = false
+			}
+		case r == '"':
+			inString = true
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			depth--
+		case unicode.IsSpace(r) && depth == 0:
+			if inToken {
+				args = append(args, current.String())
+				current.Reset()
+				inToken = false
+			}
+			continue
+		}
+		current.WriteRune(r)
+		inToken = true
+	}
+	if inString || depth != 0 {
+		return nil, i18n.Errorf(i18n.UnclosedQuoteOrBracket)
+	}
+	if inToken {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentSessionCommands(t *testing.T) {
+	e := New()
+	setup := e.NewSession(io.Discard)
+	for _, pool := range []string{"p0", "p1"} {
+		mustRun(t, setup, "add-pool "+pool)
+		for _, schema := range []string{"s0", "s1"} {
+			mustRun(t, setup,
+				fmt.Sprintf("add-schema %s %s", pool, schema),
+				fmt.Sprintf("add-collection %s %s c0", pool, schema),
+				fmt.Sprintf("add-collection %s %s c1 btree", pool, schema))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			session := e.NewSession(io.Discard)
+			defer session.Close()
+			path := fmt.Sprintf("p%d s%d c%d", w%2, w/2%2, w/4%2)
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("w%d-%d", w, i)
+				for _, command := range []string{
+					fmt.Sprintf("add-record %s %s %d", path, key, i),
+					fmt.Sprintf("read-record %s %s", path, key),
+					fmt.Sprintf("update-record %s %s {\"n\":%d}", path, key, i),
+					fmt.Sprintf("read-range %s w x", path),
+					fmt.Sprintf("delete-record %s %s", path, key),
+				} {
+					if err := RunCommand(session, command); err != nil {
+						t.Errorf("%s: %v", command, err)
+						return
+					}
+				}
+				if i%10 == 0 {
+					// undo возвращает запись, удаленную этим сеансом
+					for _, command := range []string{"undo", fmt.Sprintf("read-record %s %s", path, key)} {
+						if err := RunCommand(session, command); err != nil {
+							t.Errorf("%s: %v", command, err)
+						}
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < 16; w++ {
+		coll, err := e.Pools().LookupCollection(fmt.Sprintf("p%d", w%2), fmt.Sprintf("s%d", w/2%2), fmt.Sprintf("c%d", w/4%2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			_, err := coll.Get(fmt.Sprintf("w%d-%d", w, i))
+			if restored := i%10 == 0; restored != (err == nil) {
+				t.Fatalf("запись w%d-%d: %v", w, i, err)
+			}
+		}
+	}
+}
+
+func TestCommandProceedsWhileOtherCollectionBlocked(t *testing.T) {
+	e, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	mustRun(t, e.NewSession(io.Discard), "add-pool p", "add-schema p s", "add-collection p s a", "add-collection p s b")
+
+	// Коллекция a занята, например командой, ожидающей сброса журнала на диск
+	scope := make(orderScope)
+	scope.write("p", "s", "a")
+	release := e.order.acquire(scope)
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- RunCommand(e.NewSession(io.Discard), "add-record p s a k 1")
+	}()
+	// Дожидаемся, пока команда над a встанет в очередь за блокировкой
+	for waiting := false; !waiting; {
+		e.order.mu.Lock()
+		lock := e.order.locks[orderNameOf("p", "s", "a")]
+		waiting = lock != nil && lock.refs == 2
+		e.order.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	session := e.NewSession(io.Discard)
+	mustRun(t, session, "add-record p s b k 1", "update-record p s b k 2", "undo", "read-record p s b k")
+	select {
+	case err := <-blocked:
+		t.Fatalf("команда над занятой коллекцией a завершилась: %v", err)
+	default:
+	}
+	release()
+	if err := <-blocked; err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, session, "read-record p s a k")
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"io"
+
+	"db/catalog"
+	"db/i18n"
+)
+
+// Session состояние одного клиента: история команд для undo/redo, активная
+// транзакция и поток, в который выводятся результаты команд. Консоль и каждое
+// сетевое подключение работают в собственном сеансе над общими пулами.
+type Session struct {
+	id        uint64
+	engine    *Engine
+	pools     *catalog.AllPools
+	out       io.Writer
+	formatter Formatter // формат вывода результатов в out
+	commands  *CommandHistory
+	tx        *catalog.Transaction // активная транзакция; nil вне транзакции
+}
+
+// NewSession открывает сеанс, выводящий результаты команд в out.
+func (e *Engine) NewSession(out io.Writer) *Session {
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+	e.lastSession++
+	return e.openSession(e.lastSession, out)
+}
+
+// openSession регистрирует сеанс с заданным номером; вызывается под sessionsMu.
+func (e *Engine) openSession(id uint64, out io.Writer) *Session {
+	session := &Session{
+		id:        id,
+		engine:    e,
+		pools:     e.pools,
+		out:       out,
+		formatter: TextFormatter{},
+		commands:  NewCommandHistory(),
+	}
+	e.sessions[id] = session
+	return session
+}
+
+// replaySession открывает сеанс для повтора команд сеанса id из журнала.
+// Результаты повторяемых команд не выводятся.
+func (e *Engine) replaySession(id uint64) *Session {
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+	if id > e.lastSession {
+		e.lastSession = id
+	}
+	return e.openSession(id, io.Discard)
+}
+
+// ID возвращает номер сеанса, под которым его команды записываются в журнал.
+func (s *Session) ID() uint64 {
+	return s.id
+}
+
+// SetFormatter задает формат вывода результатов команд сеанса; по умолчанию текст.
+func (s *Session) SetFormatter(formatter Formatter) {
+	s.formatter = formatter
+}
+
+// Close завершает сеанс; незафиксированная транзакция отменяется.
+func (s *Session) Close() {
+	// Состояние транзакции читает Save под блокировкой контрольной точки
+	s.engine.checkpoint.RLock()
+	if s.tx != nil {
+		s.tx.Rollback()
+		s.tx = nil
+	}
+	s.engine.checkpoint.RUnlock()
+	s.engine.sessionsMu.Lock()
+	defer s.engine.sessionsMu.Unlock()
+	delete(s.engine.sessions, s.id)
+}
+
+// checkNoTransactions проверяет, что ни в одном сеансе нет открытой транзакции:
+// ее начало уже записано в журнал, который контрольная точка очищает.
+// Вызывается под блокировкой контрольной точки.
+func (e *Engine) checkNoTransactions() error {
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+	for _, session := range e.sessions {
+		if session.tx != nil {
+			return i18n.Errorf(i18n.SaveInTransaction)
+		}
+	}
+	return nil
+}
+
+// clearCommandHistories очищает историю команд всех сеансов после контрольной точки.
+func (e *Engine) clearCommandHistories() {
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+	for _, session := range e.sessions {
+		session.commands.Clear()
+	}
+}
@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"db/i18n"
+)
+
+const walFileName = "wal.log"
+
+// mutatingCommands команды, изменяющие данные или состояние сеанса; они записываются
+// в журнал до выполнения.
+var mutatingCommands = map[string]bool{
+	"add-pool":          true,
+	"remove-pool":       true,
+	"add-schema":        true,
+	"remove-schema":     true,
+	"add-collection":    true,
+	"remove-collection": true,
+	"add-record":        true,
+	"update-record":     true,
+	"delete-record":     true,
+	"create-index":      true,
+	"undo":              true,
+	"redo":              true,
+	"begin":             true,
+	"commit":            true,
+	"rollback":          true,
+}
+
+// transactionalCommands изменяющие команды, допустимые внутри транзакции.
+var transactionalCommands = map[string]bool{
+	"add-record":    true,
+	"update-record": true,
+	"delete-record": true,
+	"commit":        true,
+	"rollback":      true,
+}
+
+// walEntry одна запись журнала: порядковый номер, время, номер сеанса и текст команды.
+// Номер сеанса нужен, чтобы при повторе undo, redo и транзакции относились
+// к командам того же клиента. Команда, завершившаяся ошибкой, остается в журнале,
+// а за ней записывается отметка с номером этой команды в Failed.
+// В файле каждая запись занимает одну строку в формате JSON.
+type walEntry struct {
+	LSN     uint64    `json:"lsn"`
+	Time    time.Time `json:"time"`
+	Session uint64    `json:"session,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Failed  uint64    `json:"failed,omitempty"`
+}
+
+// WAL журнал упреждающей записи. Изменяющая команда дописывается в конец журнала
+// и сбрасывается на диск до того, как будет применена к данным.
+type WAL struct {
+	mu   sync.Mutex // защищает lsn, err и порядок строк в файле
+	file *os.File
+	lsn  uint64 // номер последней записанной команды
+	err  error  // ошибка записи; до Truncate новые записи не принимаются
+}
+
+// OpenWAL открывает журнал для дозаписи; lsn — номер последней уже записанной команды,
+// size — длина прочитанной части журнала. Недописанный после сбоя хвост отбрасывается.
+func OpenWAL(path string, lsn uint64, size int64) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &WAL{file: file, lsn: lsn}, nil
+}
+
+// Append записывает команду в журнал, дожидается ее сброса на диск и возвращает
+// ее номер.
+func (w *WAL) Append(session uint64, command string, t time.Time) (uint64, error) {
+	return w.append(walEntry{Time: t, Session: session, Command: command})
+}
+
+// AppendFailure отмечает, что команда с номером lsn завершилась ошибкой.
+func (w *WAL) AppendFailure(session, lsn uint64) error {
+	_, err := w.append(walEntry{Session: session, Failed: lsn})
+	return err
+}
+
+func (w *WAL) append(entry walEntry) (uint64, error) {
+	w.mu.Lock()
+	if w.err != nil {
+		w.mu.Unlock()
+		return 0, w.err
+	}
+	entry.LSN = w.lsn + 1
+	data, err := json.Marshal(entry)
+	if err != nil {
+		w.mu.Unlock()
+		return 0, err
+	}
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		// В файле могла остаться часть строки, после которой записи нельзя дописывать
+		w.err = err
+		w.mu.Unlock()
+		return 0, err
+	}
+	w.lsn = entry.LSN
+	w.mu.Unlock()
+
+	// Сброс на диск идет без блокировки: другие команды тем временем дописывают
+	// свои строки, и один сброс подтверждает все строки, записанные до него
+	if err := w.file.Sync(); err != nil {
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.mu.Unlock()
+		return 0, err
+	}
+	return entry.LSN, nil
+}
+
+// LSN возвращает номер последней записанной команды.
+func (w *WAL) LSN() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lsn
+}
+
+// Truncate очищает журнал после того, как его содержимое попало в контрольную точку.
+func (w *WAL) Truncate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	// Контрольная точка содержит все изменения, поэтому прежняя ошибка записи
+	// больше не расходится с данными
+	w.err = nil
+	return nil
+}
+
+func (w *WAL) Close() error {
+	return w.file.Close()
+}
+
+// readWAL читает записи журнала и возвращает длину прочитанной части. Последняя
+// строка, недописанная или поврежденная при сбое, считается неподтвержденной
+// и отбрасывается; поврежденная строка в середине журнала — ошибка.
+func readWAL(path string) ([]walEntry, int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var entries []walEntry
+	var size int64
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Строка без перевода строки не была дописана до конца
+			return entries, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var entry walEntry
+		if jerr := json.Unmarshal(data, &entry); jerr != nil {
+			if _, err := reader.Peek(1); errors.Is(err, io.EOF) {
+				return entries, size, nil
+			}
+			return nil, 0, i18n.Wrap(jerr, i18n.WALCorrupted, line, jerr)
+		}
+		entries = append(entries, entry)
+		size += int64(len(data))
+	}
+}
+
+// replayWAL применяет к пулам команды журнала entries, не попавшие в контрольную
+// точку, с их исходным временем, и возвращает номер последней записи журнала.
+// Команды повторяются в порядке журнала и должны завершиться так же, как при
+// первом выполнении; иначе данные разошлись с журналом и открыть базу нельзя.
+func (e *Engine) replayWAL(entries []walEntry, checkpointLSN uint64) (uint64, error) {
+	failed := make(map[uint64]bool)
+	last := make(map[uint64]uint64) // номер последней команды каждого сеанса
+	for _, entry := range entries {
+		if entry.Failed != 0 {
+			failed[entry.Failed] = true
+		} else {
+			last[entry.Session] = entry.LSN
+		}
+	}
+	// Повторяемые команды получают время из журнала по часам пулов этой базы
+	defer e.pools.SetClock(nil)
+
+	// Каждый сеанс журнала повторяется в собственном сеансе без вывода
+	sessions := make(map[uint64]*Session)
+	defer func() {
+		// Транзакции, не зафиксированные до сбоя, отбрасываются
+		for _, session := range sessions {
+			session.Close()
+		}
+	}()
+
+	lsn := checkpointLSN
+	for _, entry := range entries {
+		if entry.LSN <= lsn {
+			continue
+		}
+		lsn = entry.LSN
+		if entry.Failed != 0 {
+			continue
+		}
+		entryTime := entry.Time
+		e.pools.SetClock(func() time.Time { return entryTime })
+		session, exists := sessions[entry.Session]
+		if !exists {
+			session = e.replaySession(entry.Session)
+			sessions[entry.Session] = session
+		}
+		err := RunCommand(session, entry.Command)
+		switch {
+		case err == nil && failed[entry.LSN]:
+			return 0, i18n.Errorf(i18n.WALReplayDiverged, entry.LSN, entry.Command)
+		case err != nil && !failed[entry.LSN] && last[entry.Session] != entry.LSN:
+			// Отметки об ошибке нет только у последней команды сеанса, если сбой
+			// произошел до записи отметки: клиент не получил ответа на такую команду
+			return 0, i18n.Wrap(err, i18n.WALReplay, entry.LSN, entry.Command, err)
+		}
+	}
+	return lsn, nil
+}
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"db/tree"
+)
+
+func mustRun(t *testing.T, session *Session, commands ...string) {
+	t.Helper()
+	for _, command := range commands {
+		if err := RunCommand(session, command); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+	}
+}
+
+func readAll(t *testing.T, e *Engine) []tree.Record {
+	t.Helper()
+	coll, err := e.Pools().LookupCollection("p", "s", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := coll.GetRangeRecords("", "\xff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestWALReplayFailedCommands(t *testing.T) {
+	dir := t.TempDir()
+	e, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := e.NewSession(io.Discard)
+	mustRun(t, session, "add-pool p", "add-schema p s", "add-collection p s c", "add-record p s c k 1")
+	for _, command := range []string{"add-record p s c k 2", "update-record p s c missing 1", "add-pool p"} {
+		if err := RunCommand(session, command); err == nil {
+			t.Fatalf("%s: ожидалась ошибка", command)
+		}
+	}
+	// Неудачная фиксация завершает транзакцию: следующая команда выполняется вне ее
+	mustRun(t, session, "begin p", "add-record p s c k 3")
+	if err := RunCommand(session, "commit"); err == nil {
+		t.Fatal("commit: ожидалась ошибка")
+	}
+	mustRun(t, session, "add-record p s c j 4")
+	entries, _, err := readWAL(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Команды записываются до выполнения, а за неудачными следует отметка об ошибке
+	var logged []string
+	for _, entry := range entries {
+		if entry.Failed != 0 {
+			logged = append(logged, fmt.Sprintf("failed %d", entry.Failed))
+		} else {
+			logged = append(logged, entry.Command)
+		}
+	}
+	want := []string{"add-pool p", "add-schema p s", "add-collection p s c", "add-record p s c k 1",
+		"add-record p s c k 2", "failed 5", "update-record p s c missing 1", "failed 7", "add-pool p", "failed 9",
+		"begin p", "add-record p s c k 3", "commit", "failed 13", "add-record p s c j 4"}
+	if !reflect.DeepEqual(logged, want) {
+		t.Fatalf("журнал %q, ожидался %q", logged, want)
+	}
+	before := readAll(t, e)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if after := readAll(t, reopened); !reflect.DeepEqual(after, before) {
+		t.Fatalf("после повтора %v, до %v", after, before)
+	}
+}
+
+func TestWALReplayConcurrentSessions(t *testing.T) {
+	dir := t.TempDir()
+	e, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, e.NewSession(io.Discard), "add-pool p", "add-schema p s", "add-collection p s c")
+
+	const sessions, iterations = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			session := e.NewSession(io.Discard)
+			defer session.Close()
+			for j := 0; j < iterations; j++ {
+				// Сеансы изменяют одни и те же ключи, поэтому итог зависит от порядка команд
+				key := fmt.Sprintf("k%d", j%5)
+				if RunCommand(session, fmt.Sprintf("add-record p s c %s %d", key, i)) != nil {
+					RunCommand(session, fmt.Sprintf("update-record p s c %s %d", key, i*iterations+j))
+				}
+				if j%7 == 0 {
+					RunCommand(session, "delete-record p s c "+key)
+				}
+				if j%11 == 0 {
+					RunCommand(session, "undo")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	before := readAll(t, e)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if after := readAll(t, reopened); !reflect.DeepEqual(after, before) {
+		t.Fatalf("после повтора %v, до %v", after, before)
+	}
+}
+
+// writeWAL записывает журнал из строк lines в каталог dir.
+func writeWAL(t *testing.T, dir string, lines ...string) {
+	t.Helper()
+	data := strings.Join(lines, "\n")
+	if err := os.WriteFile(filepath.Join(dir, walFileName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func poolNames(e *Engine) []string {
+	names := e.Pools().PoolNames()
+	sort.Strings(names)
+	return names
+}
+
+func TestWALReplayErrors(t *testing.T) {
+	const (
+		addPool   = `{"lsn":1,"time":"2024-01-01T00:00:00Z","session":1,"command":"add-pool p"}`
+		addSchema = `{"lsn":2,"time":"2024-01-01T00:00:01Z","session":1,"command":"add-schema p s"}`
+		badSchema = `{"lsn":2,"time":"2024-01-01T00:00:01Z","session":1,"command":"add-schema q s"}`
+		failed2   = `{"lsn":3,"time":"0001-01-01T00:00:00Z","session":1,"failed":2}`
+		addPoolQ  = `{"lsn":4,"time":"2024-01-01T00:00:02Z","session":1,"command":"add-pool q"}`
+	)
+	tests := []struct {
+		name  string
+		lines []string
+		pools []string // nil, если журнал не должен открыться
+	}{
+		{name: "полный журнал", lines: []string{addPool, addSchema, ""}, pools: []string{"p"}},
+		{name: "недописанная строка", lines: []string{addPool, `{"lsn":2,"ti`}, pools: []string{"p"}},
+		{name: "только недописанная строка", lines: []string{`{"lsn":1,"ti`}, pools: []string{}},
+		{name: "поврежденная последняя строка", lines: []string{addPool, "garbage", ""}, pools: []string{"p"}},
+		{name: "повреждение в середине", lines: []string{addPool, "garbage", addSchema, ""}},
+		{name: "ожидаемая ошибка", lines: []string{addPool, badSchema, failed2, addPoolQ, ""}, pools: []string{"p", "q"}},
+		{name: "ошибка без отметки", lines: []string{addPool, badSchema, addPoolQ, ""}},
+		{name: "ошибка последней команды сеанса", lines: []string{addPool, badSchema, ""}, pools: []string{"p"}},
+		{name: "успех вместо ошибки", lines: []string{addPool, addSchema, failed2, ""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeWAL(t, dir, test.lines...)
+			e, err := Open(dir)
+			if test.pools == nil {
+				if err == nil {
+					e.Close()
+					t.Fatal("ожидалась ошибка открытия")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := poolNames(e); !reflect.DeepEqual(names, test.pools) {
+				t.Fatalf("пулы %v, ожидались %v", names, test.pools)
+			}
+			// Отброшенный хвост не мешает дописывать журнал
+			mustRun(t, e.NewSession(io.Discard), "add-pool r")
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+			reopened, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			if names := poolNames(reopened); !reflect.DeepEqual(names, append(test.pools, "r")) {
+				t.Fatalf("после повторного открытия пулы %v", names)
+			}
+		})
+	}
+}
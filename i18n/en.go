@@ -0,0 +1,147 @@
+package i18n
+
+var en = map[ID]string{
+	KeyNotFound:           "Record not found!",
+	DuplicateKey:          "A record with this key already exists!",
+	RecordKeyNotFound:     "Record with key %s not found!",
+	RecordKeyExists:       "Record with key %s already exists!",
+	BTreeDegreeTooSmall:   "B-tree minimum degree must be at least 2, got %d",
+	UnknownCollectionType: "Unknown collection type: %s",
+
+	PoolNotFound:        "Pool not found!",
+	SchemaNotFound:      "Schema not found!",
+	CollectionNotFound:  "Collection not found!",
+	PoolExists:          "A pool with this name already exists!",
+	SchemaExists:        "A schema with this name already exists!",
+	CollectionExists:    "A collection with this name already exists!",
+	UnknownIndexType:    "Unknown index type: %s",
+	IndexExists:         "Index on field %s already exists",
+	TransactionFinished: "Transaction is already finished",
+	SnapshotReadOnly:    "Snapshot is read-only",
+	TimeNotSpecified:    "Time is not specified",
+
+	DataFileRead:           "Error reading data file: %v",
+	DataFileVersion:        "Unsupported data file version: %d",
+	CollectionRestore:      "Collection %s.%s.%s: %v",
+	DataDirNotSet:          "Data directory is not set",
+	WALWrite:               "Error writing to the log: %v",
+	WALCorrupted:           "Log is corrupted: line %d: %v",
+	WALReplay:              "Log command %d (%s) failed on replay: %v",
+	WALReplayDiverged:      "Log command %d (%s) succeeded on replay although it had failed",
+	SaveInTransaction:      "Cannot save while a transaction is open.",
+	UnclosedQuoteOrBracket: "Unclosed quote or bracket in command",
+
+	InvalidValue:          "Invalid value %s: %v",
+	TrailingCharacters:    "Invalid value %s: unexpected characters after value",
+	InvalidNumber:         "Invalid number %s",
+	FieldsNotArray:        "Field definitions must be an array",
+	FieldNotObject:        "Field definition must be an object: %s",
+	FieldNameMissing:      "Field name is not specified",
+	FieldDuplicate:        "Field %s is defined more than once",
+	UnknownFieldType:      "Unknown type of field %s: %s",
+	FieldNameNotString:    "Field name must be a string",
+	FieldTypeNotString:    "Field type must be a string",
+	FieldRequiredNotBool:  "The required attribute must be true or false",
+	UnknownFieldAttribute: "Unknown field attribute: %s",
+	RecordNotObject:       "Invalid record: expected an object, got %s",
+	RecordMissingField:    "Invalid record: missing required field %s",
+	InvalidRecord:         "Invalid record: %v",
+	RecordUnknownFields:   "Invalid record: unknown fields %s",
+	FieldTypeMismatch:     "field %s must have type %s, got %s",
+
+	QueryError:            "Query error: %v",
+	QueryUnclosedQuote:    "Unclosed quote in query",
+	QueryInvalidChar:      "Invalid character in query: %q",
+	QueryExpected:         "expected %s, got %q",
+	QueryExpectedName:     "expected a name, got %q",
+	QueryCollectionPath:   "collection must be given as pool.schema.collection, got %q",
+	QueryUnexpectedToken:  "unexpected token %q",
+	QueryExpectedCount:    "expected a non-negative integer, got %q",
+	QueryExpectedParen:    "expected ), got %q",
+	QueryExpectedPattern:  "expected a pattern string, got %q",
+	QueryExpectedLike:     "expected like, got %q",
+	QueryExpectedOperator: "expected a comparison operator, got %q",
+	QueryExpectedLiteral:  "expected a literal, got %q",
+	UnknownAggregate:      "Unknown aggregate function: %s",
+	StarOnlyForCount:      "Field * is allowed only for the count function",
+
+	NoCommand:            "No command provided",
+	UnknownCommand:       "Unknown command.",
+	NotEnoughArguments:   "Not enough arguments for command %s.",
+	CommandInTransaction: "Command %s is not available inside a transaction.",
+	InvalidBTreeDegree:   "Invalid B-tree degree: %s",
+	GroupByExpected:      "Expected group-by <field>.",
+	InvalidPageSize:      "Invalid page size: %s",
+	InvalidTime:          "Invalid time %s, expected RFC3339 format.",
+	NothingToUndo:        "No commands to undo",
+	NothingToRedo:        "No commands to redo",
+	PoolAdded:            "Added pool %s",
+	PoolRemoved:          "Pool %s removed.",
+	SchemaAdded:          "Schema %s added to pool %s",
+	SchemaRemoved:        "Schema %s removed from pool %s",
+	CollectionAdded:      "Collection %s added to schema %s in pool %s",
+	CollectionRemoved:    "Collection %s removed from schema %s in pool %s",
+	RecordAdded:          "Record with key %s added",
+	RecordUpdated:        "Record with key %s updated.",
+	RecordDeleted:        "Record with key %s deleted.",
+	IndexCreated:         "Index on field %s created in collection %s",
+	CommandUndone:        "Command undone.",
+	CommandRedone:        "Command redone.",
+	DataSaved:            "Data saved to %s",
+
+	TransactionAlreadyStarted: "Transaction already started.",
+	TransactionStarted:        "Transaction started in pool %s",
+	NoActiveTransaction:       "No active transaction.",
+	TransactionAborted:        "Transaction aborted: %v",
+	TransactionCommitted:      "Transaction committed.",
+	TransactionRolledBack:     "Transaction rolled back.",
+	TransactionOtherPool:      "Transaction started in pool %s, operations on pool %s are not available.",
+	OperationBuffered:         "Operation added to the transaction.",
+
+	UnknownFormat:   "Unknown output format: %s",
+	UnknownLanguage: "Unknown language: %s",
+	TotalRecords:    "Total records: %d",
+	Continuation:    "Continue after: %s",
+	VersionDeleted:  "%s: deleted, was: %v",
+	VersionReplaced: "%s: %v, was: %v",
+	CommandError:    "Command failed: %v",
+
+	EnterCommand:  "Enter a command:",
+	LoadError:     "Error loading data: %v",
+	SaveError:     "Error saving data: %v",
+	FileOpenError: "Error opening file: %v",
+	FileReadError: "Error reading file: %v",
+	InputError:    "Input error: %v",
+	ConnectError:  "Connection error: %v",
+	ServerIOError: "Error communicating with the server: %v",
+	FlagDataDir:   "directory for storing data on disk",
+	FlagListen:    "address to accept network connections on, e.g. :7070",
+	FlagHTTP:      "HTTP API address, e.g. :8080",
+	FlagFormat:    "result output format: text or json",
+	FlagLang:      "message language: ru or en (defaults to LANG)",
+	FlagAddr:      "server address",
+	FlagTimeout:   "connection timeout",
+
+	ServerListening:         "Server is accepting connections on %s",
+	HTTPListening:           "HTTP API is available on %s",
+	ServerError:             "Server error: %v",
+	ServerStopping:          "Stopping server...",
+	ServerStopError:         "Error stopping server: %v",
+	HTTPStopError:           "Error stopping HTTP API: %v",
+	ServerClosed:            "Server is closed",
+	ResourceNotFound:        "Resource not found: %s",
+	InvalidName:             "Invalid name: %q",
+	MethodNotAllowed:        "Method %s is not supported for %s",
+	PoolNamedExists:         "Pool %s already exists",
+	PoolNamedNotFound:       "Pool %s not found",
+	SchemaNamedExists:       "Schema %s already exists in pool %s",
+	SchemaNamedNotFound:     "Schema %s not found in pool %s",
+	CollectionNamedExists:   "Collection %s already exists in schema %s",
+	CollectionNamedNotFound: "Collection %s not found in schema %s",
+	RecordNamedExists:       "Record %s already exists",
+	RecordNamedNotFound:     "Record %s not found",
+	InvalidFields:           "Invalid field definitions: %v",
+	RecordValueMissing:      "Record value is not specified",
+	BodyReadError:           "Error reading request body: %v",
+	BodyTooLarge:            "Request body is larger than %d bytes",
+}
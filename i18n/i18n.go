@@ -0,0 +1,124 @@
+// Package i18n содержит каталоги сообщений на русском и английском языках.
+// Команды и ошибки хранят идентификатор сообщения и его аргументы, а текст
+// подставляется при выводе на языке, выбранном SetLanguage.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ID идентификатор сообщения в каталогах.
+type ID string
+
+// DefaultLanguage язык сообщений по умолчанию; его каталог используется и для
+// сообщений, которых нет в каталоге выбранного языка.
+const DefaultLanguage = "ru"
+
+var catalogs = map[string]map[ID]string{
+	"ru": ru,
+	"en": en,
+}
+
+var language atomic.Value // string
+
+// SetLanguage выбирает язык сообщений. Принимает код языка ("ru", "en") или
+// значение локали вида en_US.UTF-8.
+func SetLanguage(lang string) error {
+	name := normalize(lang)
+	if _, ok := catalogs[name]; !ok {
+		return Errorf(UnknownLanguage, lang)
+	}
+	language.Store(name)
+	return nil
+}
+
+// Language возвращает код выбранного языка сообщений.
+func Language() string {
+	if name, ok := language.Load().(string); ok {
+		return name
+	}
+	return DefaultLanguage
+}
+
+// EnvLanguage возвращает поддерживаемый язык из переменных окружения LC_ALL,
+// LC_MESSAGES и LANG (в порядке приоритета) или пустую строку.
+func EnvLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if lang := normalize(value); catalogs[lang] != nil {
+			return lang
+		}
+		return ""
+	}
+	return ""
+}
+
+// normalize приводит локаль вида en_US.UTF-8 к коду языка.
+func normalize(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_.@-"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// Sprintf возвращает текст сообщения id на выбранном языке с подставленными
+// аргументами. Аргументы-ошибки выводятся своим текстом, то есть тоже на выбранном языке.
+func Sprintf(id ID, args ...interface{}) string {
+	format, ok := catalogs[Language()][id]
+	if !ok {
+		if format, ok = catalogs[DefaultLanguage][id]; !ok {
+			return string(id)
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Message сообщение с идентификатором и аргументами; текст выбирается при выводе.
+type Message struct {
+	ID   ID
+	Args []interface{}
+}
+
+// NewMessage создает сообщение id с аргументами args.
+func NewMessage(id ID, args ...interface{}) Message {
+	return Message{ID: id, Args: args}
+}
+
+func (m Message) String() string {
+	if m.ID == "" {
+		return ""
+	}
+	return Sprintf(m.ID, m.Args...)
+}
+
+// Error ошибка с идентификатором сообщения. Err — ошибка-причина, доступная
+// через errors.Is и errors.As; nil, если ошибка не оборачивает другую.
+type Error struct {
+	Message
+	Err error
+}
+
+// Errorf создает ошибку с сообщением id и аргументами args.
+func Errorf(id ID, args ...interface{}) error {
+	return &Error{Message: NewMessage(id, args...)}
+}
+
+// Wrap создает ошибку с сообщением id, оборачивающую err.
+func Wrap(err error, id ID, args ...interface{}) error {
+	return &Error{Message: NewMessage(id, args...), Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
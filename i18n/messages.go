@@ -0,0 +1,159 @@
+package i18n
+
+// Идентификаторы сообщений. Тексты на каждом языке задаются в ru.go и en.go
+// и принимают одинаковые аргументы.
+const (
+	// Записи и деревья
+	KeyNotFound           ID = "key_not_found"
+	DuplicateKey          ID = "duplicate_key"
+	RecordKeyNotFound     ID = "record_key_not_found"
+	RecordKeyExists       ID = "record_key_exists"
+	BTreeDegreeTooSmall   ID = "btree_degree_too_small"
+	UnknownCollectionType ID = "unknown_collection_type"
+
+	// Каталог
+	PoolNotFound        ID = "pool_not_found"
+	SchemaNotFound      ID = "schema_not_found"
+	CollectionNotFound  ID = "collection_not_found"
+	PoolExists          ID = "pool_exists"
+	SchemaExists        ID = "schema_exists"
+	CollectionExists    ID = "collection_exists"
+	UnknownIndexType    ID = "unknown_index_type"
+	IndexExists         ID = "index_exists"
+	TransactionFinished ID = "transaction_finished"
+	TimeNotSpecified    ID = "time_not_specified"
+	SnapshotReadOnly    ID = "snapshot_read_only"
+
+	// Хранение на диске
+	DataFileRead           ID = "data_file_read"
+	DataFileVersion        ID = "data_file_version"
+	CollectionRestore      ID = "collection_restore"
+	DataDirNotSet          ID = "data_dir_not_set"
+	WALWrite               ID = "wal_write"
+	WALCorrupted           ID = "wal_corrupted"
+	WALReplay              ID = "wal_replay"
+	WALReplayDiverged      ID = "wal_replay_diverged"
+	SaveInTransaction      ID = "save_in_transaction"
+	UnclosedQuoteOrBracket ID = "unclosed_quote_or_bracket"
+
+	// Значения и описания полей
+	InvalidValue          ID = "invalid_value"
+	TrailingCharacters    ID = "trailing_characters"
+	InvalidNumber         ID = "invalid_number"
+	FieldsNotArray        ID = "fields_not_array"
+	FieldNotObject        ID = "field_not_object"
+	FieldNameMissing      ID = "field_name_missing"
+	FieldDuplicate        ID = "field_duplicate"
+	UnknownFieldType      ID = "unknown_field_type"
+	FieldNameNotString    ID = "field_name_not_string"
+	FieldTypeNotString    ID = "field_type_not_string"
+	FieldRequiredNotBool  ID = "field_required_not_bool"
+	UnknownFieldAttribute ID = "unknown_field_attribute"
+	RecordNotObject       ID = "record_not_object"
+	RecordMissingField    ID = "record_missing_field"
+	InvalidRecord         ID = "invalid_record"
+	RecordUnknownFields   ID = "record_unknown_fields"
+	FieldTypeMismatch     ID = "field_type_mismatch"
+
+	// Запросы и агрегатные функции
+	QueryError            ID = "query_error"
+	QueryUnclosedQuote    ID = "query_unclosed_quote"
+	QueryInvalidChar      ID = "query_invalid_char"
+	QueryExpected         ID = "query_expected"
+	QueryExpectedName     ID = "query_expected_name"
+	QueryCollectionPath   ID = "query_collection_path"
+	QueryUnexpectedToken  ID = "query_unexpected_token"
+	QueryExpectedCount    ID = "query_expected_count"
+	QueryExpectedParen    ID = "query_expected_paren"
+	QueryExpectedPattern  ID = "query_expected_pattern"
+	QueryExpectedLike     ID = "query_expected_like"
+	QueryExpectedOperator ID = "query_expected_operator"
+	QueryExpectedLiteral  ID = "query_expected_literal"
+	UnknownAggregate      ID = "unknown_aggregate"
+	StarOnlyForCount      ID = "star_only_for_count"
+
+	// Команды
+	NoCommand            ID = "no_command"
+	UnknownCommand       ID = "unknown_command"
+	NotEnoughArguments   ID = "not_enough_arguments"
+	CommandInTransaction ID = "command_in_transaction"
+	InvalidBTreeDegree   ID = "invalid_btree_degree"
+	GroupByExpected      ID = "group_by_expected"
+	InvalidPageSize      ID = "invalid_page_size"
+	InvalidTime          ID = "invalid_time"
+	NothingToUndo        ID = "nothing_to_undo"
+	NothingToRedo        ID = "nothing_to_redo"
+	PoolAdded            ID = "pool_added"
+	PoolRemoved          ID = "pool_removed"
+	SchemaAdded          ID = "schema_added"
+	SchemaRemoved        ID = "schema_removed"
+	CollectionAdded      ID = "collection_added"
+	CollectionRemoved    ID = "collection_removed"
+	RecordAdded          ID = "record_added"
+	RecordUpdated        ID = "record_updated"
+	RecordDeleted        ID = "record_deleted"
+	IndexCreated         ID = "index_created"
+	CommandUndone        ID = "command_undone"
+	CommandRedone        ID = "command_redone"
+	DataSaved            ID = "data_saved"
+
+	// Транзакции
+	TransactionAlreadyStarted ID = "transaction_already_started"
+	TransactionStarted        ID = "transaction_started"
+	NoActiveTransaction       ID = "no_active_transaction"
+	TransactionAborted        ID = "transaction_aborted"
+	TransactionCommitted      ID = "transaction_committed"
+	TransactionRolledBack     ID = "transaction_rolled_back"
+	TransactionOtherPool      ID = "transaction_other_pool"
+	OperationBuffered         ID = "operation_buffered"
+
+	// Вывод результатов
+	UnknownFormat   ID = "unknown_format"
+	UnknownLanguage ID = "unknown_language"
+	TotalRecords    ID = "total_records"
+	Continuation    ID = "continuation"
+	VersionDeleted  ID = "version_deleted"
+	VersionReplaced ID = "version_replaced"
+	CommandError    ID = "command_error"
+
+	// Консоль и клиент
+	EnterCommand  ID = "enter_command"
+	LoadError     ID = "load_error"
+	SaveError     ID = "save_error"
+	FileOpenError ID = "file_open_error"
+	FileReadError ID = "file_read_error"
+	InputError    ID = "input_error"
+	ConnectError  ID = "connect_error"
+	ServerIOError ID = "server_io_error"
+	FlagDataDir   ID = "flag_data_dir"
+	FlagListen    ID = "flag_listen"
+	FlagHTTP      ID = "flag_http"
+	FlagFormat    ID = "flag_format"
+	FlagLang      ID = "flag_lang"
+	FlagAddr      ID = "flag_addr"
+	FlagTimeout   ID = "flag_timeout"
+
+	// Сервер
+	ServerListening         ID = "server_listening"
+	HTTPListening           ID = "http_listening"
+	ServerError             ID = "server_error"
+	ServerStopping          ID = "server_stopping"
+	ServerStopError         ID = "server_stop_error"
+	HTTPStopError           ID = "http_stop_error"
+	ServerClosed            ID = "server_closed"
+	ResourceNotFound        ID = "resource_not_found"
+	InvalidName             ID = "invalid_name"
+	MethodNotAllowed        ID = "method_not_allowed"
+	PoolNamedExists         ID = "pool_named_exists"
+	PoolNamedNotFound       ID = "pool_named_not_found"
+	SchemaNamedExists       ID = "schema_named_exists"
+	SchemaNamedNotFound     ID = "schema_named_not_found"
+	CollectionNamedExists   ID = "collection_named_exists"
+	CollectionNamedNotFound ID = "collection_named_not_found"
+	RecordNamedExists       ID = "record_named_exists"
+	RecordNamedNotFound     ID = "record_named_not_found"
+	InvalidFields           ID = "invalid_fields"
+	RecordValueMissing      ID = "record_value_missing"
+	BodyReadError           ID = "body_read_error"
+	BodyTooLarge            ID = "body_too_large"
+)
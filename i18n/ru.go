@@ -0,0 +1,147 @@
+package i18n
+
+var ru = map[ID]string{
+	KeyNotFound:           "Элемент не найден!",
+	DuplicateKey:          "Элемент с таким ключом уже существует!",
+	RecordKeyNotFound:     "Элемент с ключом %s не найден!",
+	RecordKeyExists:       "Элемент с ключом %s уже существует!",
+	BTreeDegreeTooSmall:   "Минимальная степень B-дерева должна быть не меньше 2, получено %d",
+	UnknownCollectionType: "Неизвестный тип коллекции: %s",
+
+	PoolNotFound:        "Пул не найден!",
+	SchemaNotFound:      "Схема не найдена!",
+	CollectionNotFound:  "Коллекция не найдена!",
+	PoolExists:          "Пул с таким именем уже существует!",
+	SchemaExists:        "Схема с таким именем уже существует!",
+	CollectionExists:    "Коллекция с таким именем уже существует!",
+	UnknownIndexType:    "Неизвестный тип индекса: %s",
+	IndexExists:         "Индекс по полю %s уже существует",
+	TransactionFinished: "Транзакция уже завершена",
+	SnapshotReadOnly:    "Срез доступен только для чтения",
+	TimeNotSpecified:    "Не указано время",
+
+	DataFileRead:           "Ошибка чтения файла данных: %v",
+	DataFileVersion:        "Неподдерживаемая версия файла данных: %d",
+	CollectionRestore:      "Коллекция %s.%s.%s: %v",
+	DataDirNotSet:          "Не задан каталог данных",
+	WALWrite:               "Ошибка записи в журнал: %v",
+	WALCorrupted:           "Журнал поврежден: строка %d: %v",
+	WALReplay:              "Команда %d журнала (%s) не выполнена при повторе: %v",
+	WALReplayDiverged:      "Команда %d журнала (%s) выполнена при повторе, хотя завершилась ошибкой",
+	SaveInTransaction:      "Сохранение невозможно, пока открыта транзакция.",
+	UnclosedQuoteOrBracket: "Незакрытая кавычка или скобка в команде",
+
+	InvalidValue:          "Некорректное значение %s: %v",
+	TrailingCharacters:    "Некорректное значение %s: лишние символы после значения",
+	InvalidNumber:         "Некорректное число %s",
+	FieldsNotArray:        "Описание полей должно быть массивом",
+	FieldNotObject:        "Описание поля должно быть объектом: %s",
+	FieldNameMissing:      "Не указано имя поля",
+	FieldDuplicate:        "Поле %s описано несколько раз",
+	UnknownFieldType:      "Неизвестный тип поля %s: %s",
+	FieldNameNotString:    "Имя поля должно быть строкой",
+	FieldTypeNotString:    "Тип поля должен быть строкой",
+	FieldRequiredNotBool:  "Признак required должен быть true или false",
+	UnknownFieldAttribute: "Неизвестный атрибут поля: %s",
+	RecordNotObject:       "Некорректная запись: ожидается объект, получено %s",
+	RecordMissingField:    "Некорректная запись: отсутствует обязательное поле %s",
+	InvalidRecord:         "Некорректная запись: %v",
+	RecordUnknownFields:   "Некорректная запись: неизвестные поля %s",
+	FieldTypeMismatch:     "поле %s должно иметь тип %s, получено %s",
+
+	QueryError:            "Ошибка в запросе: %v",
+	QueryUnclosedQuote:    "Незакрытая кавычка в запросе",
+	QueryInvalidChar:      "Недопустимый символ в запросе: %q",
+	QueryExpected:         "ожидается %s, получено %q",
+	QueryExpectedName:     "ожидается имя, получено %q",
+	QueryCollectionPath:   "коллекция задается как пул.схема.коллекция, получено %q",
+	QueryUnexpectedToken:  "неожиданный токен %q",
+	QueryExpectedCount:    "ожидается неотрицательное целое число, получено %q",
+	QueryExpectedParen:    "ожидается ), получено %q",
+	QueryExpectedPattern:  "ожидается строка-шаблон, получено %q",
+	QueryExpectedLike:     "ожидается like, получено %q",
+	QueryExpectedOperator: "ожидается оператор сравнения, получено %q",
+	QueryExpectedLiteral:  "ожидается литерал, получено %q",
+	UnknownAggregate:      "Неизвестная агрегатная функция: %s",
+	StarOnlyForCount:      "Поле * допустимо только для функции count",
+
+	NoCommand:            "Не указана команда",
+	UnknownCommand:       "Неизвестная команда.",
+	NotEnoughArguments:   "Недостаточно аргументов для команды %s.",
+	CommandInTransaction: "Команда %s недоступна внутри транзакции.",
+	InvalidBTreeDegree:   "Некорректная степень B-дерева: %s",
+	GroupByExpected:      "Ожидается group-by <поле>.",
+	InvalidPageSize:      "Некорректный размер страницы: %s",
+	InvalidTime:          "Некорректное время %s, ожидается формат RFC3339.",
+	NothingToUndo:        "Нет команд для отмены",
+	NothingToRedo:        "Нет команд для повтора",
+	PoolAdded:            "Добавлен пул с именем %s",
+	PoolRemoved:          "Пул с именем %s удален.",
+	SchemaAdded:          "Схема с именем %s добавлена в пул %s",
+	SchemaRemoved:        "Схема с именем %s удалена из пула %s",
+	CollectionAdded:      "Коллекция с именем %s добавлена в схему %s в пул %s",
+	CollectionRemoved:    "Коллекция с именем %s удалена из схемы %s из пула %s",
+	RecordAdded:          "Элемент успешно добавлен с ключом %s",
+	RecordUpdated:        "Значение элемента с ключом %s успешно обновлено.",
+	RecordDeleted:        "Элемент с ключом %s удален.",
+	IndexCreated:         "Индекс по полю %s создан в коллекции %s",
+	CommandUndone:        "Команда отменена.",
+	CommandRedone:        "Команда выполнена повторно.",
+	DataSaved:            "Данные сохранены в %s",
+
+	TransactionAlreadyStarted: "Транзакция уже начата.",
+	TransactionStarted:        "Транзакция начата в пуле %s",
+	NoActiveTransaction:       "Нет активной транзакции.",
+	TransactionAborted:        "Транзакция отменена: %v",
+	TransactionCommitted:      "Транзакция зафиксирована.",
+	TransactionRolledBack:     "Транзакция отменена.",
+	TransactionOtherPool:      "Транзакция начата в пуле %s, операции над пулом %s недоступны.",
+	OperationBuffered:         "Операция добавлена в транзакцию.",
+
+	UnknownFormat:   "Неизвестный формат вывода: %s",
+	UnknownLanguage: "Неизвестный язык: %s",
+	TotalRecords:    "Всего записей: %d",
+	Continuation:    "Продолжение: %s",
+	VersionDeleted:  "%s: удалено, было: %v",
+	VersionReplaced: "%s: %v, было: %v",
+	CommandError:    "Ошибка выполнения команды: %v",
+
+	EnterCommand:  "Введите команду:",
+	LoadError:     "Ошибка загрузки данных: %v",
+	SaveError:     "Ошибка сохранения данных: %v",
+	FileOpenError: "Ошибка открытия файла: %v",
+	FileReadError: "Ошибка чтения файла: %v",
+	InputError:    "Ошибка ввода: %v",
+	ConnectError:  "Ошибка подключения: %v",
+	ServerIOError: "Ошибка связи с сервером: %v",
+	FlagDataDir:   "каталог для хранения данных на диске",
+	FlagListen:    "адрес для приема сетевых подключений, например :7070",
+	FlagHTTP:      "адрес HTTP API, например :8080",
+	FlagFormat:    "формат вывода результатов: text или json",
+	FlagLang:      "язык сообщений: ru или en (по умолчанию из LANG)",
+	FlagAddr:      "адрес сервера",
+	FlagTimeout:   "время ожидания подключения",
+
+	ServerListening:         "Сервер принимает подключения на %s",
+	HTTPListening:           "HTTP API доступен на %s",
+	ServerError:             "Ошибка сервера: %v",
+	ServerStopping:          "Остановка сервера...",
+	ServerStopError:         "Ошибка остановки сервера: %v",
+	HTTPStopError:           "Ошибка остановки HTTP API: %v",
+	ServerClosed:            "Сервер остановлен",
+	ResourceNotFound:        "Ресурс не найден: %s",
+	InvalidName:             "Недопустимое имя: %q",
+	MethodNotAllowed:        "Метод %s не поддерживается для %s",
+	PoolNamedExists:         "Пул %s уже существует",
+	PoolNamedNotFound:       "Пул %s не найден",
+	SchemaNamedExists:       "Схема %s уже существует в пуле %s",
+	SchemaNamedNotFound:     "Схема %s не найдена в пуле %s",
+	CollectionNamedExists:   "Коллекция %s уже существует в схеме %s",
+	CollectionNamedNotFound: "Коллекция %s не найдена в схеме %s",
+	RecordNamedExists:       "Запись %s уже существует",
+	RecordNamedNotFound:     "Запись %s не найдена",
+	InvalidFields:           "Некорректное описание полей: %v",
+	RecordValueMissing:      "Не задано значение записи",
+	BodyReadError:           "Ошибка чтения тела запроса: %v",
+	BodyTooLarge:            "Тело запроса больше %d байт",
+}
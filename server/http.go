@@ -0,0 +1,479 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"db/catalog"
+	"db/engine"
+	"db/i18n"
+)
+
+// HTTP API.
+//
+// Иерархия пулов отображается на ресурсы:
+//
+//	GET    /pools                                         имена пулов
+//	PUT    /pools/{p}                                     создать пул
+//	DELETE /pools/{p}                                     удалить пул
+//	GET    /pools/{p}/schemas                             имена схем
+//	PUT    /pools/{p}/schemas/{s}                         создать схему
+//	DELETE /pools/{p}/schemas/{s}                         удалить схему
+//	GET    /pools/{p}/schemas/{s}/collections             имена коллекций
+//	PUT    /pools/{p}/schemas/{s}/collections/{c}         создать коллекцию (?type=, ?degree=,
+//	                                                      в теле — необязательное описание полей)
+//	DELETE /pools/{p}/schemas/{s}/collections/{c}         удалить коллекцию
+//	GET    /pools/{p}/schemas/{s}/collections/{c}/records записи: ?min=&max= — диапазон ключей
+//	                                                      (любую границу можно опустить),
+//	                                                      иначе ?prefix=&limit=&after= — страница
+//	GET    .../records/{key}                              прочитать запись
+//	POST   .../records/{key}                              вставить запись, значение в теле
+//	PUT    .../records/{key}                              изменить запись, значение в теле
+//	DELETE .../records/{key}                              удалить запись
+//
+// Значения записей передаются в теле в синтаксисе ParseValue, в том числе как JSON,
+// ответы — в JSON. Ошибки возвращаются телом {"error": "..."}; ошибки команд дополнительно
+// содержат код {"code": "..."} (см. engine.ErrorCode). Изменения выполняются
+// теми же командами, что и в консоли, поэтому записываются в журнал.
+
+// HTTPHandler обслуживает HTTP API над пулами.
+type HTTPHandler struct {
+	db    *engine.Engine
+	pools *catalog.AllPools
+}
+
+func NewHTTPHandler(db *engine.Engine) *HTTPHandler {
+	return &HTTPHandler{db: db, pools: db.Pools()}
+}
+
+// httpError ошибка запроса с кодом ответа и, для ошибок команд, кодом ошибки.
+type httpError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func httpErrorf(status int, id i18n.ID, args ...interface{}) *httpError {
+	return &httpError{status: status, message: i18n.Sprintf(id, args...)}
+}
+
+// jsonRecord запись в ответе API.
+type jsonRecord struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// jsonPage страница записей; Next — токен продолжения для параметра after.
+type jsonPage struct {
+	Records []jsonRecord `json:"records"`
+	Next    string       `json:"next,omitempty"`
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, body, err := h.route(r)
+	if err != nil {
+		status = http.StatusInternalServerError
+		response := map[string]string{"error": err.Error()}
+		if e, ok := err.(*httpError); ok {
+			status = e.status
+			if e.code != "" {
+				response["code"] = e.code
+			}
+		}
+		body = response
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if body != nil && status != http.StatusNoContent {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// route разбирает путь запроса и вызывает обработчик ресурса.
+func (h *HTTPHandler) route(r *http.Request) (int, interface{}, error) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) == 0 || segments[0] != "pools" {
+		return 0, nil, httpErrorf(http.StatusNotFound, i18n.ResourceNotFound, r.URL.Path)
+	}
+	// Имена чередуются с названиями уровней: pools/{p}/schemas/{s}/collections/{c}/records/{key}
+	levels := []string{"pools", "schemas", "collections", "records"}
+	var names []string
+	for i, segment := range segments {
+		if i%2 == 0 {
+			if i/2 >= len(levels) || segment != levels[i/2] {
+				return 0, nil, httpErrorf(http.StatusNotFound, i18n.ResourceNotFound, r.URL.Path)
+			}
+			continue
+		}
+		if err := checkName(segment); err != nil {
+			return 0, nil, err
+		}
+		names = append(names, segment)
+	}
+	listing := len(segments)%2 == 1
+
+	switch {
+	case len(names) == 0:
+		return h.poolList(r)
+	case len(names) == 1 && !listing:
+		return h.pool(r, names[0])
+	case len(names) == 1:
+		return h.schemaList(r, names[0])
+	case len(names) == 2 && !listing:
+		return h.schema(r, names[0], names[1])
+	case len(names) == 2:
+		return h.collectionList(r, names[0], names[1])
+	case len(names) == 3 && !listing:
+		return h.collection(r, names[0], names[1], names[2])
+	case len(names) == 3:
+		return h.recordList(r, names[0], names[1], names[2])
+	default:
+		return h.record(r, names[0], names[1], names[2], names[3])
+	}
+}
+
+// checkName проверяет, что имя можно передать аргументом команды.
+func checkName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n\"{}[]") {
+		return httpErrorf(http.StatusBadRequest, i18n.InvalidName, name)
+	}
+	return nil
+}
+
+func methodNotAllowed(r *http.Request) (int, interface{}, error) {
+	return 0, nil, httpErrorf(http.StatusMethodNotAllowed, i18n.MethodNotAllowed, r.Method, r.URL.Path)
+}
+
+// run выполняет изменяющую команду в отдельном сеансе.
+func (h *HTTPHandler) run(command string) error {
+	session := h.db.NewSession(io.Discard)
+	defer session.Close()
+	if _, err := engine.Execute(session, command); err != nil {
+		return &httpError{status: commandStatus(err), code: engine.ErrorCode(err), message: err.Error()}
+	}
+	return nil
+}
+
+// commandStatus возвращает код ответа для ошибки команды.
+func commandStatus(err error) int {
+	switch {
+	case errors.Is(err, catalog.ErrPoolNotFound), errors.Is(err, catalog.ErrSchemaNotFound),
+		errors.Is(err, catalog.ErrCollectionNotFound), errors.Is(err, catalog.ErrKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, catalog.ErrPoolExists), errors.Is(err, catalog.ErrSchemaExists),
+		errors.Is(err, catalog.ErrCollectionExists), errors.Is(err, catalog.ErrDuplicateKey):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// poolList возвращает имена пулов.
+func (h *HTTPHandler) poolList(r *http.Request) (int, interface{}, error) {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(r)
+	}
+	names := h.pools.PoolNames()
+	sort.Strings(names)
+	return http.StatusOK, names, nil
+}
+
+func (h *HTTPHandler) pool(r *http.Request, poolName string) (int, interface{}, error) {
+	_, err := h.pools.GetPool(poolName)
+	exists := err == nil
+	switch r.Method {
+	case http.MethodPut:
+		if exists {
+			return 0, nil, httpErrorf(http.StatusConflict, i18n.PoolNamedExists, poolName)
+		}
+		if err := h.run("add-pool " + poolName); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, map[string]string{"pool": poolName}, nil
+	case http.MethodDelete:
+		if !exists {
+			return 0, nil, httpErrorf(http.StatusNotFound, i18n.PoolNamedNotFound, poolName)
+		}
+		if err := h.run("remove-pool " + poolName); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+func (h *HTTPHandler) lookupPool(poolName string) (*catalog.Pool, error) {
+	pool, err := h.pools.GetPool(poolName)
+	if err != nil {
+		return nil, httpErrorf(http.StatusNotFound, i18n.PoolNamedNotFound, poolName)
+	}
+	return pool, nil
+}
+
+func (h *HTTPHandler) lookupSchema(poolName, schemaName string) (*catalog.Schema, error) {
+	pool, err := h.lookupPool(poolName)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := pool.GetSchema(schemaName)
+	if err != nil {
+		return nil, httpErrorf(http.StatusNotFound, i18n.SchemaNamedNotFound, schemaName, poolName)
+	}
+	return schema, nil
+}
+
+func (h *HTTPHandler) lookupCollection(poolName, schemaName, collectionName string) (*catalog.VersionedCollection, error) {
+	schema, err := h.lookupSchema(poolName, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := schema.GetVersionedCollection(collectionName)
+	if err != nil {
+		return nil, httpErrorf(http.StatusNotFound, i18n.CollectionNamedNotFound, collectionName, schemaName)
+	}
+	return collection, nil
+}
+
+// schemaList возвращает имена схем пула.
+func (h *HTTPHandler) schemaList(r *http.Request, poolName string) (int, interface{}, error) {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(r)
+	}
+	pool, err := h.lookupPool(poolName)
+	if err != nil {
+		return 0, nil, err
+	}
+	names := pool.SchemaNames()
+	sort.Strings(names)
+	return http.StatusOK, names, nil
+}
+
+func (h *HTTPHandler) schema(r *http.Request, poolName, schemaName string) (int, interface{}, error) {
+	pool, err := h.lookupPool(poolName)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = pool.GetSchema(schemaName)
+	exists := err == nil
+	switch r.Method {
+	case http.MethodPut:
+		if exists {
+			return 0, nil, httpErrorf(http.StatusConflict, i18n.SchemaNamedExists, schemaName, poolName)
+		}
+		if err := h.run(fmt.Sprintf("add-schema %s %s", poolName, schemaName)); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, map[string]string{"pool": poolName, "schema": schemaName}, nil
+	case http.MethodDelete:
+		if !exists {
+			return 0, nil, httpErrorf(http.StatusNotFound, i18n.SchemaNamedNotFound, schemaName, poolName)
+		}
+		if err := h.run(fmt.Sprintf("remove-schema %s %s", poolName, schemaName)); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+// collectionList возвращает имена коллекций схемы.
+func (h *HTTPHandler) collectionList(r *http.Request, poolName, schemaName string) (int, interface{}, error) {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(r)
+	}
+	schema, err := h.lookupSchema(poolName, schemaName)
+	if err != nil {
+		return 0, nil, err
+	}
+	names := schema.CollectionNames()
+	sort.Strings(names)
+	return http.StatusOK, names, nil
+}
+
+func (h *HTTPHandler) collection(r *http.Request, poolName, schemaName, collectionName string) (int, interface{}, error) {
+	schema, err := h.lookupSchema(poolName, schemaName)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = schema.GetVersionedCollection(collectionName)
+	exists := err == nil
+	switch r.Method {
+	case http.MethodPut:
+		if exists {
+			return 0, nil, httpErrorf(http.StatusConflict, i18n.CollectionNamedExists, collectionName, schemaName)
+		}
+		command := fmt.Sprintf("add-collection %s %s %s", poolName, schemaName, collectionName)
+		query := r.URL.Query()
+		collectionType := query.Get("type")
+		if collectionType == "" {
+			collectionType = "map"
+		}
+		if err := checkName(collectionType); err != nil {
+			return 0, nil, err
+		}
+		command += " " + collectionType
+		if degree := query.Get("degree"); degree != "" {
+			if _, err := strconv.Atoi(degree); err != nil {
+				return 0, nil, httpErrorf(http.StatusBadRequest, i18n.InvalidBTreeDegree, degree)
+			}
+			command += " " + degree
+		}
+		body, err := readBody(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(body) > 0 {
+			// Описание полей передается одним аргументом команды
+			var fields bytes.Buffer
+			if err := json.Compact(&fields, body); err != nil {
+				return 0, nil, httpErrorf(http.StatusBadRequest, i18n.InvalidFields, err)
+			}
+			command += " " + fields.String()
+		}
+		if err := h.run(command); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, map[string]string{"pool": poolName, "schema": schemaName, "collection": collectionName}, nil
+	case http.MethodDelete:
+		if !exists {
+			return 0, nil, httpErrorf(http.StatusNotFound, i18n.CollectionNamedNotFound, collectionName, schemaName)
+		}
+		if err := h.run(fmt.Sprintf("remove-collection %s %s %s", poolName, schemaName, collectionName)); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+// recordList возвращает записи коллекции по диапазону ключей или постранично по префиксу.
+func (h *HTTPHandler) recordList(r *http.Request, poolName, schemaName, collectionName string) (int, interface{}, error) {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(r)
+	}
+	versioned, err := h.lookupCollection(poolName, schemaName, collectionName)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Записи читаются из среза и не задерживают изменения коллекции
+	collection := h.pools.Snapshot().View(versioned)
+	query := r.URL.Query()
+	page := jsonPage{Records: []jsonRecord{}}
+	if query.Has("min") || query.Has("max") {
+		// Отсутствующая граница не ограничивает диапазон
+		var lower, upper *string
+		if query.Has("min") {
+			minValue := query.Get("min")
+			lower = &minValue
+		}
+		if query.Has("max") {
+			maxValue := query.Get("max")
+			upper = &maxValue
+		}
+		records, err := catalog.ScanRange(collection, lower, upper)
+		if err != nil {
+			return 0, nil, &httpError{status: http.StatusBadRequest, message: err.Error()}
+		}
+		for _, record := range records {
+			page.Records = append(page.Records, jsonRecord{Key: record.Key, Value: record.Value})
+		}
+		return http.StatusOK, page, nil
+	}
+	limit := catalog.DefaultPageSize
+	if text := query.Get("limit"); text != "" {
+		if limit, err = strconv.Atoi(text); err != nil || limit <= 0 {
+			return 0, nil, httpErrorf(http.StatusBadRequest, i18n.InvalidPageSize, text)
+		}
+	}
+	records, next := catalog.ScanPrefix(collection, query.Get("prefix"), limit, query.Get("after"))
+	for _, record := range records {
+		page.Records = append(page.Records, jsonRecord{Key: record.Key, Value: record.Value})
+	}
+	page.Next = next
+	return http.StatusOK, page, nil
+}
+
+func (h *HTTPHandler) record(r *http.Request, poolName, schemaName, collectionName, key string) (int, interface{}, error) {
+	collection, err := h.lookupCollection(poolName, schemaName, collectionName)
+	if err != nil {
+		return 0, nil, err
+	}
+	current, err := h.pools.Snapshot().View(collection).Get(key)
+	exists := err == nil
+	path := fmt.Sprintf("%s %s %s %s", poolName, schemaName, collectionName, key)
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			return 0, nil, httpErrorf(http.StatusNotFound, i18n.RecordNamedNotFound, key)
+		}
+		return http.StatusOK, jsonRecord{Key: key, Value: current}, nil
+	case http.MethodPost, http.MethodPut:
+		if r.Method == http.MethodPost && exists {
+			return 0, nil, httpErrorf(http.StatusConflict, i18n.RecordNamedExists, key)
+		}
+		if r.Method == http.MethodPut && !exists {
+			return 0, nil, httpErrorf(http.StatusNotFound, i18n.RecordNamedNotFound, key)
+		}
+		body, err := readBody(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(body) == 0 {
+			return 0, nil, httpErrorf(http.StatusBadRequest, i18n.RecordValueMissing)
+		}
+		value, err := catalog.ParseValue(strings.TrimSpace(string(body)))
+		if err != nil {
+			return 0, nil, &httpError{status: http.StatusBadRequest, message: err.Error()}
+		}
+		command, status := "add-record", http.StatusCreated
+		if r.Method == http.MethodPut {
+			command, status = "update-record", http.StatusOK
+		}
+		if err := h.run(command + " " + path + " " + catalog.FormatValue(value)); err != nil {
+			return 0, nil, err
+		}
+		// Возвращается сохраненное значение с учетом значений полей по умолчанию
+		if stored, err := collection.Get(key); err == nil {
+			value = stored
+		}
+		return status, jsonRecord{Key: key, Value: value}, nil
+	case http.MethodDelete:
+		if !exists {
+			return 0, nil, httpErrorf(http.StatusNotFound, i18n.RecordNamedNotFound, key)
+		}
+		if err := h.run("delete-record " + path); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+// maxBodySize наибольший размер тела запроса в байтах.
+const maxBodySize = 16 * 1024 * 1024
+
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, httpErrorf(http.StatusBadRequest, i18n.BodyReadError, err)
+	}
+	if len(body) > maxBodySize {
+		return nil, httpErrorf(http.StatusRequestEntityTooLarge, i18n.BodyTooLarge, maxBodySize)
+	}
+	return bytes.TrimSpace(body), nil
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"db/engine"
+)
+
+// httpStep запрос к API и ожидаемый ответ. Для успешных ответов body сравнивается
+// с ожидаемым JSON; для ошибок проверяется, что тело содержит error и код code.
+type httpStep struct {
+	method string
+	path   string
+	body   string
+	status int
+	want   string
+	code   string
+}
+
+func runHTTPSteps(t *testing.T, server *httptest.Server, steps []httpStep) {
+	t.Helper()
+	for _, step := range steps {
+		request, err := http.NewRequest(step.method, server.URL+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := step.method + " " + step.path
+		if response.StatusCode != step.status {
+			t.Fatalf("%s: статус %d, ожидался %d; тело %s", name, response.StatusCode, step.status, body)
+		}
+		if contentType := response.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+			t.Fatalf("%s: Content-Type %q", name, contentType)
+		}
+		if step.status == http.StatusNoContent {
+			if len(body) != 0 {
+				t.Fatalf("%s: тело %s в ответе без содержимого", name, body)
+			}
+			continue
+		}
+		if step.status >= 400 {
+			var failure struct {
+				Error string `json:"error"`
+				Code  string `json:"code"`
+			}
+			if err := json.Unmarshal(body, &failure); err != nil || failure.Error == "" {
+				t.Fatalf("%s: тело ошибки %s (%v)", name, body, err)
+			}
+			if failure.Code != step.code {
+				t.Fatalf("%s: код ошибки %q, ожидался %q", name, failure.Code, step.code)
+			}
+			continue
+		}
+		var got, want interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("%s: тело %s: %v", name, body, err)
+		}
+		if err := json.Unmarshal([]byte(step.want), &want); err != nil {
+			t.Fatalf("%s: ожидаемое тело %s: %v", name, step.want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: тело %s, ожидалось %s", name, bytes.TrimSpace(body), step.want)
+		}
+	}
+}
+
+func newHTTPTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(NewHTTPHandler(engine.New()))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPHierarchy(t *testing.T) {
+	const c = "/pools/p/schemas/s/collections/c"
+	runHTTPSteps(t, newHTTPTestServer(t), []httpStep{
+		{method: "GET", path: "/pools", status: 200, want: `[]`},
+		{method: "PUT", path: "/pools/p", status: 201, want: `{"pool":"p"}`},
+		{method: "PUT", path: "/pools/p", status: 409},
+		{method: "PUT", path: "/pools/q", status: 201, want: `{"pool":"q"}`},
+		{method: "GET", path: "/pools", status: 200, want: `["p","q"]`},
+		{method: "POST", path: "/pools", status: 405},
+		{method: "POST", path: "/pools/p", status: 405},
+
+		{method: "GET", path: "/pools/p/schemas", status: 200, want: `[]`},
+		{method: "GET", path: "/pools/x/schemas", status: 404},
+		{method: "PUT", path: "/pools/p/schemas/s", status: 201, want: `{"pool":"p","schema":"s"}`},
+		{method: "PUT", path: "/pools/p/schemas/s", status: 409},
+		{method: "PUT", path: "/pools/x/schemas/s", status: 404},
+		{method: "GET", path: "/pools/p/schemas", status: 200, want: `["s"]`},
+		{method: "DELETE", path: "/pools/p/schemas", status: 405},
+		{method: "GET", path: "/pools/p/schemas/s", status: 405},
+
+		{method: "GET", path: "/pools/p/schemas/s/collections", status: 200, want: `[]`},
+		{method: "GET", path: "/pools/p/schemas/x/collections", status: 404},
+		{method: "PUT", path: c, status: 201, want: `{"pool":"p","schema":"s","collection":"c"}`},
+		{method: "PUT", path: c, status: 409},
+		{method: "PUT", path: "/pools/p/schemas/s/collections/t?type=btree&degree=3", status: 201,
+			want: `{"pool":"p","schema":"s","collection":"t"}`},
+		{method: "PUT", path: "/pools/p/schemas/s/collections/u?type=btree&degree=x", status: 400},
+		{method: "PUT", path: "/pools/p/schemas/s/collections/u?type=unknown", status: 400, code: engine.CodeError},
+		{method: "PUT", path: "/pools/p/schemas/s/collections/u", body: `{not json`, status: 400},
+		{method: "PUT", path: "/pools/p/schemas/s/collections/v",
+			body: `[{"name":"n","type":"int","required":true}]`, status: 201,
+			want: `{"pool":"p","schema":"s","collection":"v"}`},
+		{method: "GET", path: "/pools/p/schemas/s/collections", status: 200, want: `["c","t","v"]`},
+		{method: "POST", path: "/pools/p/schemas/s/collections", status: 405},
+		{method: "GET", path: c, status: 405},
+
+		{method: "DELETE", path: "/pools/p/schemas/s/collections/t", status: 204},
+		{method: "DELETE", path: "/pools/p/schemas/s/collections/t", status: 404},
+		{method: "DELETE", path: "/pools/q/schemas/s", status: 404},
+		{method: "DELETE", path: "/pools/q", status: 204},
+		{method: "DELETE", path: "/pools/q", status: 404},
+		{method: "GET", path: "/pools", status: 200, want: `["p"]`},
+
+		{method: "GET", path: "/", status: 404},
+		{method: "GET", path: "/other", status: 404},
+		{method: "GET", path: "/pools/p/tables", status: 404},
+		{method: "GET", path: "/pools/p/schemas/s/collections/c/records/k/extra", status: 404},
+		{method: "PUT", path: "/pools/a%20b", status: 400},
+
+		{method: "DELETE", path: "/pools/p/schemas/s", status: 204},
+		{method: "GET", path: "/pools/p/schemas", status: 200, want: `[]`},
+	})
+}
+
+func TestHTTPRecords(t *testing.T) {
+	const c = "/pools/p/schemas/s/collections/c"
+	const v = "/pools/p/schemas/s/collections/v"
+	runHTTPSteps(t, newHTTPTestServer(t), []httpStep{
+		{method: "PUT", path: "/pools/p", status: 201, want: `{"pool":"p"}`},
+		{method: "PUT", path: "/pools/p/schemas/s", status: 201, want: `{"pool":"p","schema":"s"}`},
+		{method: "PUT", path: c + "?type=avl", status: 201, want: `{"pool":"p","schema":"s","collection":"c"}`},
+		{method: "PUT", path: v, body: `[{"name":"n","type":"int","required":true}]`, status: 201,
+			want: `{"pool":"p","schema":"s","collection":"v"}`},
+
+		{method: "GET", path: c + "/records", status: 200, want: `{"records":[]}`},
+		{method: "POST", path: c + "/records/b", body: `2`, status: 201, want: `{"key":"b","value":2}`},
+		{method: "POST", path: c + "/records/b", body: `3`, status: 409},
+		{method: "POST", path: c + "/records/a", body: `{"x":"y"}`, status: 201, want: `{"key":"a","value":{"x":"y"}}`},
+		{method: "POST", path: c + "/records/c", body: `"three"`, status: 201, want: `{"key":"c","value":"three"}`},
+		{method: "POST", path: c + "/records/d", status: 400},
+		{method: "POST", path: c + "/records/d", body: `{broken`, status: 400},
+		{method: "GET", path: c + "/records/a", status: 200, want: `{"key":"a","value":{"x":"y"}}`},
+		{method: "GET", path: c + "/records/z", status: 404},
+		{method: "PUT", path: c + "/records/b", body: `20`, status: 200, want: `{"key":"b","value":20}`},
+		{method: "PUT", path: c + "/records/z", body: `1`, status: 404},
+		{method: "PATCH", path: c + "/records/b", body: `1`, status: 405},
+		{method: "POST", path: c + "/records", status: 405},
+
+		// Диапазоны ключей, в том числе с одной границей
+		{method: "GET", path: c + "/records?min=a&max=b", status: 200,
+			want: `{"records":[{"key":"a","value":{"x":"y"}},{"key":"b","value":20}]}`},
+		{method: "GET", path: c + "/records?min=b", status: 200,
+			want: `{"records":[{"key":"b","value":20},{"key":"c","value":"three"}]}`},
+		{method: "GET", path: c + "/records?max=b", status: 200,
+			want: `{"records":[{"key":"a","value":{"x":"y"}},{"key":"b","value":20}]}`},
+		{method: "GET", path: c + "/records?min=x", status: 200, want: `{"records":[]}`},
+
+		// Постраничное чтение по префиксу
+		{method: "GET", path: c + "/records?limit=2", status: 200,
+			want: `{"records":[{"key":"a","value":{"x":"y"}},{"key":"b","value":20}],"next":"b"}`},
+		{method: "GET", path: c + "/records?limit=2&after=b", status: 200,
+			want: `{"records":[{"key":"c","value":"three"}]}`},
+		{method: "GET", path: c + "/records?prefix=c", status: 200, want: `{"records":[{"key":"c","value":"three"}]}`},
+		{method: "GET", path: c + "/records?limit=0", status: 400},
+		{method: "GET", path: c + "/records?limit=x", status: 400},
+
+		{method: "DELETE", path: c + "/records/b", status: 204},
+		{method: "DELETE", path: c + "/records/b", status: 404},
+		{method: "GET", path: c + "/records/b", status: 404},
+		{method: "GET", path: "/pools/p/schemas/s/collections/x/records", status: 404},
+		{method: "GET", path: "/pools/p/schemas/s/collections/x/records/a", status: 404},
+
+		// Записи проверяются по описанию полей коллекции
+		{method: "POST", path: v + "/records/k", body: `{"n":1}`, status: 201, want: `{"key":"k","value":{"n":1}}`},
+		{method: "POST", path: v + "/records/l", body: `{"n":"one"}`, status: 400, code: engine.CodeError},
+		{method: "POST", path: v + "/records/l", body: `{}`, status: 400, code: engine.CodeError},
+		{method: "PUT", path: v + "/records/k", body: `{"m":1}`, status: 400, code: engine.CodeError},
+		{method: "GET", path: v + "/records/k", status: 200, want: `{"key":"k","value":{"n":1}}`},
+	})
+}
@@ -0,0 +1,186 @@
+// Package server предоставляет доступ к базе данных по сети: построчный протокол
+// поверх TCP и HTTP/JSON API над пулами, схемами, коллекциями и записями.
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"db/engine"
+	"db/i18n"
+)
+
+// Сетевой протокол.
+//
+// Клиент отправляет по одной команде в строке, в той же грамматике, что и
+// консоль. На каждую команду сервер отвечает одной строкой JSON в формате
+// engine.JSONFormatter — результатом команды или ошибкой:
+//
+//	{"ok":true,"kind":"records","records":[{"key":"a","value":1}],"count":1}
+//	{"ok":false,"code":"key_not_found","error":"Элемент не найден!"}
+//
+// Каждое подключение работает в собственном сеансе: undo, redo и транзакции
+// относятся только к командам этого подключения. Команда exit закрывает подключение.
+// Поле code ответа с ошибкой содержит машиночитаемый код (см. engine.ErrorCode).
+
+// maxCommandSize наибольшая длина одной команды в байтах.
+const maxCommandSize = 16 * 1024 * 1024
+
+// ShutdownTimeout время, за которое при остановке должны завершиться начатые команды.
+const ShutdownTimeout = 10 * time.Second
+
+// Server обслуживает клиентов по TCP над общими пулами.
+type Server struct {
+	db *engine.Engine
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+func NewServer(db *engine.Engine) *Server {
+	return &Server{
+		db:    db,
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe принимает подключения на адресе addr до вызова Shutdown.
+func (srv *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(listener)
+}
+
+// Serve принимает подключения из listener до вызова Shutdown.
+func (srv *Server) Serve(listener net.Listener) error {
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		listener.Close()
+		return i18n.Errorf(i18n.ServerClosed)
+	}
+	srv.listener = listener
+	srv.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			srv.mu.Lock()
+			closing := srv.closing
+			srv.mu.Unlock()
+			if closing {
+				return nil
+			}
+			return err
+		}
+		if !srv.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go srv.serveConn(conn)
+	}
+}
+
+// Addr возвращает адрес, на котором сервер принимает подключения.
+func (srv *Server) Addr() net.Addr {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listener == nil {
+		return nil
+	}
+	return srv.listener.Addr()
+}
+
+// track регистрирует подключение; возвращает false, если сервер уже останавливается.
+func (srv *Server) track(conn net.Conn) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.closing {
+		return false
+	}
+	srv.conns[conn] = struct{}{}
+	srv.wg.Add(1)
+	return true
+}
+
+func (srv *Server) untrack(conn net.Conn) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.conns, conn)
+	srv.wg.Done()
+}
+
+// serveConn выполняет команды одного подключения в отдельном сеансе.
+func (srv *Server) serveConn(conn net.Conn) {
+	defer srv.untrack(conn)
+	defer conn.Close()
+
+	// Результаты команд выводятся не в поток сеанса, а в ответ клиенту
+	session := srv.db.NewSession(io.Discard)
+	defer session.Close()
+	formatter := engine.JSONFormatter{}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxCommandSize)
+	for scanner.Scan() {
+		command := scanner.Text()
+		if command == "" {
+			continue
+		}
+		result, err := engine.Execute(session, command)
+		if err != nil {
+			err = formatter.FormatError(conn, err)
+		} else {
+			err = formatter.FormatResult(conn, result)
+		}
+		if err != nil {
+			return
+		}
+		if command == "exit" {
+			return
+		}
+	}
+}
+
+// Shutdown прекращает прием подключений, дает выполняющимся командам
+// завершиться и закрывает подключения. Если ctx завершится раньше,
+// подключения закрываются принудительно и возвращается ошибка ctx.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.closing = true
+	if srv.listener != nil {
+		srv.listener.Close()
+	}
+	// Чтение следующей команды прерывается сразу, а уже начатая команда
+	// выполняется до конца и получает ответ
+	for conn := range srv.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	srv.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		srv.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.mu.Lock()
+		for conn := range srv.conns {
+			conn.Close()
+		}
+		srv.mu.Unlock()
+		return ctx.Err()
+	}
+}
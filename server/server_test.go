@@ -0,0 +1,313 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"db/engine"
+)
+
+// testServer сервер, запущенный тестом, и адрес, на котором он принимает подключения.
+type testServer struct {
+	*Server
+	db   *engine.Engine
+	addr string
+}
+
+// startServer запускает сервер на свободном порту и останавливает его в конце теста.
+func startServer(t *testing.T, wrap func(net.Listener) net.Listener) *testServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrap != nil {
+		listener = wrap(listener)
+	}
+	db := engine.New()
+	srv := NewServer(db)
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return &testServer{Server: srv, db: db, addr: listener.Addr().String()}
+}
+
+// testResponse ответ сервера в объеме, нужном тестам.
+type testResponse struct {
+	OK        bool   `json:"ok"`
+	Kind      string `json:"kind"`
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+	MessageID string `json:"message_id"`
+	Record    *struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	} `json:"record"`
+	Count *int `json:"count"`
+}
+
+type testClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func dialServer(t *testing.T, srv *testServer) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func (c *testClient) send(command string) {
+	if _, err := io.WriteString(c.conn, command+"\n"); err != nil {
+		c.t.Error(err)
+	}
+}
+
+func (c *testClient) receive() testResponse {
+	var resp testResponse
+	if !c.scanner.Scan() {
+		c.t.Errorf("нет ответа: %v", c.scanner.Err())
+		return resp
+	}
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		c.t.Errorf("ответ %s: %v", c.scanner.Bytes(), err)
+	}
+	return resp
+}
+
+func (c *testClient) run(command string) testResponse {
+	c.send(command)
+	return c.receive()
+}
+
+// mustRun выполняет команды и проверяет, что все они завершились успешно.
+func (c *testClient) mustRun(commands ...string) {
+	c.t.Helper()
+	for _, command := range commands {
+		if resp := c.run(command); !resp.OK {
+			c.t.Fatalf("%s: %s", command, resp.Error)
+		}
+	}
+}
+
+func TestServerStructuredResponses(t *testing.T) {
+	srv := startServer(t, nil)
+	c := dialServer(t, srv)
+	c.mustRun("add-pool p", "add-schema p s", "add-collection p s c")
+
+	resp := c.run("add-record p s c k {\"n\":1}")
+	if !resp.OK || resp.Kind != "message" || resp.MessageID != "record_added" {
+		t.Fatalf("add-record: %+v", resp)
+	}
+	resp = c.run("read-record p s c k")
+	if !resp.OK || resp.Kind != "record" || resp.Record == nil || resp.Record.Key != "k" || string(resp.Record.Value) != `{"n":1}` {
+		t.Fatalf("read-record: %+v", resp)
+	}
+	resp = c.run("read-range p s c a z")
+	if !resp.OK || resp.Kind != "records" || resp.Count == nil || *resp.Count != 1 {
+		t.Fatalf("read-range: %+v", resp)
+	}
+	resp = c.run("read-record p s c missing")
+	if resp.OK || resp.Code != engine.CodeKeyNotFound || resp.Error == "" {
+		t.Fatalf("read-record missing: %+v", resp)
+	}
+	resp = c.run("read-record nope s c k")
+	if resp.OK || resp.Code != engine.CodePoolNotFound {
+		t.Fatalf("read-record в несуществующем пуле: %+v", resp)
+	}
+	if resp := c.run("no-such-command"); resp.OK || resp.Code != engine.CodeError {
+		t.Fatalf("неизвестная команда: %+v", resp)
+	}
+}
+
+func TestServerConcurrentClients(t *testing.T) {
+	srv := startServer(t, nil)
+	dialServer(t, srv).mustRun("add-pool p", "add-schema p s", "add-collection p s c")
+
+	const clients, records = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := dialServer(t, srv)
+			for j := 0; j < records; j++ {
+				key := fmt.Sprintf("c%d-%02d", i, j)
+				if resp := c.run(fmt.Sprintf("add-record p s c %s %d", key, j)); !resp.OK {
+					t.Errorf("add-record %s: %s", key, resp.Error)
+					return
+				}
+				if resp := c.run("read-record p s c " + key); !resp.OK || resp.Record == nil || string(resp.Record.Value) != fmt.Sprint(j) {
+					t.Errorf("read-record %s: %+v", key, resp)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	coll, err := srv.db.Pools().LookupCollection("p", "s", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := coll.GetRangeRecords("", "\xff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != clients*records {
+		t.Fatalf("%d записей, ожидалось %d", len(all), clients*records)
+	}
+}
+
+func TestServerPerConnectionSessions(t *testing.T) {
+	srv := startServer(t, nil)
+	a, b := dialServer(t, srv), dialServer(t, srv)
+	a.mustRun("add-pool p", "add-schema p s", "add-collection p s c", "add-record p s c k 1")
+
+	// undo отменяет только команды своего подключения
+	if resp := b.run("undo"); resp.OK {
+		t.Fatal("undo во втором подключении отменил чужую команду")
+	}
+	a.mustRun("undo")
+	if resp := b.run("read-record p s c k"); resp.OK || resp.Code != engine.CodeKeyNotFound {
+		t.Fatalf("после undo: %+v", resp)
+	}
+
+	// Изменения транзакции не видны другим подключениям до фиксации
+	a.mustRun("begin p", "add-record p s c t 2")
+	if resp := b.run("read-record p s c t"); resp.OK {
+		t.Fatal("незафиксированная запись видна другому подключению")
+	}
+	if resp := b.run("commit"); resp.OK {
+		t.Fatal("commit закрыл транзакцию другого подключения")
+	}
+	a.mustRun("commit")
+	if resp := b.run("read-record p s c t"); !resp.OK {
+		t.Fatalf("после фиксации: %+v", resp)
+	}
+}
+
+func TestServerExitClosesConnection(t *testing.T) {
+	srv := startServer(t, nil)
+	c := dialServer(t, srv)
+	if resp := c.run("exit"); !resp.OK {
+		t.Fatalf("exit: %+v", resp)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if c.scanner.Scan() {
+		t.Fatalf("после exit получен ответ %s", c.scanner.Bytes())
+	}
+	if err := c.scanner.Err(); err != nil {
+		t.Fatalf("после exit: %v, ожидалось закрытие подключения", err)
+	}
+}
+
+// blockingListener выдает подключения, запись ответа в которые ждет release.
+type blockingListener struct {
+	net.Listener
+	writing chan struct{} // закрывается, когда сервер начал писать ответ
+	release chan struct{}
+	once    sync.Once
+}
+
+type blockingConn struct {
+	net.Conn
+	listener *blockingListener
+}
+
+func (l *blockingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &blockingConn{Conn: conn, listener: l}, nil
+}
+
+func (c *blockingConn) Write(p []byte) (int, error) {
+	c.listener.once.Do(func() { close(c.listener.writing) })
+	<-c.listener.release
+	return c.Conn.Write(p)
+}
+
+func TestServerShutdownWaitsForCommand(t *testing.T) {
+	blocking := &blockingListener{writing: make(chan struct{}), release: make(chan struct{})}
+	srv := startServer(t, func(l net.Listener) net.Listener {
+		blocking.Listener = l
+		return blocking
+	})
+	if err := srv.db.Pools().AddPool("p"); err != nil {
+		t.Fatal(err)
+	}
+	c := dialServer(t, srv)
+	c.send("add-schema p s")
+	<-blocking.writing
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- srv.Shutdown(context.Background())
+	}()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown завершился до ответа на команду: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(blocking.release)
+	if resp := c.receive(); !resp.OK || resp.MessageID != "schema_added" {
+		t.Fatalf("ответ на начатую команду: %+v", resp)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	pool, _ := srv.db.Pools().GetPool("p")
+	if _, err := pool.GetSchema("s"); err != nil {
+		t.Fatalf("команда не выполнена: %v", err)
+	}
+	// Новые подключения после остановки не принимаются
+	if conn, err := net.Dial("tcp", srv.addr); err == nil {
+		conn.Close()
+		t.Fatal("сервер принял подключение после Shutdown")
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	blocking := &blockingListener{writing: make(chan struct{}), release: make(chan struct{})}
+	srv := startServer(t, func(l net.Listener) net.Listener {
+		blocking.Listener = l
+		return blocking
+	})
+	c := dialServer(t, srv)
+	c.send("add-pool p")
+	<-blocking.writing
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown: %v, ожидалось %v", err, context.DeadlineExceeded)
+	}
+	close(blocking.release)
+}
@@ -1,8 +1,4 @@
-package main
-
-import (
-	"errors"
-)
+package tree
 
 // Node представляет собой узел АВЛ-дерева
 type Node struct {
@@ -25,6 +21,10 @@ func NewAVLTree() *AVLTree {
 
 // Insert вставляет новый ключ со значением в дерево
 func (avl *AVLTree) Insert(key string, value interface{}) error {
+	// Проверяем ключ заранее: при ошибке внутри рекурсии поддерево было бы потеряно
+	if _, err := getNode(avl.root, key); err == nil {
+		return ErrDuplicateKey
+	}
 	var err error
 	avl.root, err = insert(avl.root, key, value)
 	return err
@@ -42,22 +42,18 @@ func (avl *AVLTree) Get(key string) (interface{}, error) {
 // GetRange возвращает список ключей в заданном диапазоне значений
 func (avl *AVLTree) GetRange(minValue, maxValue string) ([]string, error) {
 	var result []string
-	var getRangeHelper func(node *Node, minValue, maxValue string)
-	getRangeHelper = func(node *Node, minValue, maxValue string) {
-		if node == nil {
-			return
-		}
-		if node.key >= minValue {
-			getRangeHelper(node.left, minValue, maxValue)
-		}
-		if node.key >= minValue && node.key <= maxValue {
-			result = append(result, node.key)
-		}
-		if node.key <= maxValue {
-			getRangeHelper(node.right, minValue, maxValue)
-		}
-	}
-	getRangeHelper(avl.root, minValue, maxValue)
+	rangeNodes(avl.root, minValue, maxValue, func(node *Node) {
+		result = append(result, node.key)
+	})
+	return result, nil
+}
+
+// GetRangeRecords возвращает пары ключ-значение в заданном диапазоне значений
+func (avl *AVLTree) GetRangeRecords(minValue, maxValue string) ([]Record, error) {
+	var result []Record
+	rangeNodes(avl.root, minValue, maxValue, func(node *Node) {
+		result = append(result, Record{Key: node.key, Value: node.value})
+	})
 	return result, nil
 }
 
@@ -73,11 +69,33 @@ func (avl *AVLTree) Update(key string, value interface{}) error {
 
 // Remove удаляет узел с заданным ключом из дерева
 func (avl *AVLTree) Remove(key string) error {
+	if _, err := getNode(avl.root, key); err != nil {
+		return err
+	}
 	var err error
 	avl.root, err = deleteNode(avl.root, key)
 	return err
 }
 
+// ForEach обходит узлы дерева в порядке возрастания ключей, пока fn возвращает true
+func (avl *AVLTree) ForEach(fn func(key string, value interface{}) bool) {
+	forEachNode(avl.root, fn)
+}
+
+// forEachNode выполняет симметричный обход поддерева; возвращает false, если обход прерван
+func forEachNode(node *Node, fn func(key string, value interface{}) bool) bool {
+	if node == nil {
+		return true
+	}
+	if !forEachNode(node.left, fn) {
+		return false
+	}
+	if !fn(node.key, node.value) {
+		return false
+	}
+	return forEachNode(node.right, fn)
+}
+
 
 
 
@@ -159,7 +177,7 @@ func insert(node *Node, key string, value interface{}) (*Node, error) {
 			return nil, err
 		}
 	} else {
-		return nil, errors.New("Элемент с таким ключом уже существует!")
+		return nil, ErrDuplicateKey
 	}
 
 	node.height = 1 + max(height(node.left), height(node.right))
@@ -200,7 +218,7 @@ func minValueNode(node *Node) *Node {
 // и возвращает новый корень поддерева
 func deleteNode(root *Node, key string) (*Node, error) {
 	if root == nil {
-		return root, errors.New("Элемент не найден!")
+		return root, ErrKeyNotFound
 	}
 
 	if key < root.key {
@@ -271,10 +289,26 @@ func deleteNode(root *Node, key string) (*Node, error) {
 	return root, nil
 }
 
+// rangeNodes обходит по порядку узлы поддерева с ключами из диапазона [minValue, maxValue]
+func rangeNodes(node *Node, minValue, maxValue string, fn func(node *Node)) {
+	if node == nil {
+		return
+	}
+	if node.key > minValue {
+		rangeNodes(node.left, minValue, maxValue, fn)
+	}
+	if node.key >= minValue && node.key <= maxValue {
+		fn(node)
+	}
+	if node.key < maxValue {
+		rangeNodes(node.right, minValue, maxValue, fn)
+	}
+}
+
 // getNode возвращает узел с данным ключом
 func getNode(node *Node, key string) (*Node, error) {
 	if node == nil {
-		return nil, errors.New("Элемент не найден!")
+		return nil, ErrKeyNotFound
 	}
 
 	if key < node.key {
@@ -299,6 +333,9 @@ func NewAVLCollection() *AVLCollection {
 }
 
 func (avl *AVLCollection) Insert(key string, value interface{}) error {
+	if _, err := getNode(avl.tree.root, key); err == nil {
+		return ErrDuplicateKey
+	}
 	var err error
 	avl.tree.root, err = insert(avl.tree.root, key, value)
 	return err
@@ -313,24 +350,11 @@ func (avl *AVLCollection) Get(key string) (interface{}, error) {
 }
 
 func (avl *AVLCollection) GetRange(minValue, maxValue string) ([]string, error) {
-	var result []string
-	var getRangeHelper func(node *Node, minValue, maxValue string)
-	getRangeHelper = func(node *Node, minValue, maxValue string) {
-		if node == nil {
-			return
-		}
-		if node.key >= minValue {
-			getRangeHelper(node.left, minValue, maxValue)
-		}
-		if node.key >= minValue && node.key <= maxValue {
-			result = append(result, node.key) // конвертируем node.key в строку
-		}
-		if node.key <= maxValue {
-			getRangeHelper(node.right, minValue, maxValue)
-		}
-	}
-	getRangeHelper(avl.tree.root, minValue, maxValue)
-	return result, nil
+	return avl.tree.GetRange(minValue, maxValue)
+}
+
+func (avl *AVLCollection) GetRangeRecords(minValue, maxValue string) ([]Record, error) {
+	return avl.tree.GetRangeRecords(minValue, maxValue)
 }
 
 
@@ -344,7 +368,129 @@ func (avl *AVLCollection) Update(key string, value interface{}) error {
 }
 
 func (avl *AVLCollection) Remove(key string) error {
+	if _, err := getNode(avl.tree.root, key); err != nil {
+		return err
+	}
 	var err error
 	avl.tree.root, err = deleteNode(avl.tree.root, key)
 	return err
 }
+
+func (avl *AVLCollection) ForEach(fn func(key string, value interface{}) bool) {
+	forEachNode(avl.tree.root, fn)
+}
+
+// avlCursor курсор по АВЛ-дереву. Узлы не хранят ссылку на родителя,
+// поэтому курсор хранит путь от корня до текущего узла.
+type avlCursor struct {
+	tree *AVLTree
+	path []*Node
+}
+
+// Cursor возвращает курсор по дереву
+func (avl *AVLTree) Cursor() Cursor {
+	return &avlCursor{tree: avl}
+}
+
+func (avl *AVLCollection) Cursor() Cursor {
+	return avl.tree.Cursor()
+}
+
+func (c *avlCursor) Seek(key string) bool {
+	c.path = c.path[:0]
+	candidate := -1
+	for node := c.tree.root; node != nil; {
+		c.path = append(c.path, node)
+		if key == node.key {
+			candidate = len(c.path) - 1
+			break
+		}
+		if key < node.key {
+			candidate = len(c.path) - 1
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	// Путь до наименьшего ключа, не меньшего key, является началом пройденного пути
+	c.path = c.path[:candidate+1]
+	return c.Valid()
+}
+
+func (c *avlCursor) First() bool {
+	c.path = c.path[:0]
+	c.descend(c.tree.root, true)
+	return c.Valid()
+}
+
+func (c *avlCursor) Last() bool {
+	c.path = c.path[:0]
+	c.descend(c.tree.root, false)
+	return c.Valid()
+}
+
+// descend спускается от узла до крайнего левого (left) или правого узла поддерева
+func (c *avlCursor) descend(node *Node, left bool) {
+	for node != nil {
+		c.path = append(c.path, node)
+		if left {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+}
+
+func (c *avlCursor) Next() bool {
+	if !c.Valid() {
+		return false
+	}
+	current := c.path[len(c.path)-1]
+	if current.right != nil {
+		c.descend(current.right, true)
+		return true
+	}
+	// Поднимаемся, пока текущий узел является правым потомком
+	for {
+		child := c.path[len(c.path)-1]
+		c.path = c.path[:len(c.path)-1]
+		if len(c.path) == 0 || c.path[len(c.path)-1].left == child {
+			return c.Valid()
+		}
+	}
+}
+
+func (c *avlCursor) Prev() bool {
+	if !c.Valid() {
+		return false
+	}
+	current := c.path[len(c.path)-1]
+	if current.left != nil {
+		c.descend(current.left, false)
+		return true
+	}
+	// Поднимаемся, пока текущий узел является левым потомком
+	for {
+		child := c.path[len(c.path)-1]
+		c.path = c.path[:len(c.path)-1]
+		if len(c.path) == 0 || c.path[len(c.path)-1].right == child {
+			return c.Valid()
+		}
+	}
+}
+
+func (c *avlCursor) Valid() bool {
+	return len(c.path) > 0
+}
+
+func (c *avlCursor) Key() string {
+	return c.path[len(c.path)-1].key
+}
+
+func (c *avlCursor) Value() interface{} {
+	return c.path[len(c.path)-1].value
+}
+
+func (c *avlCursor) Close() {
+	c.path = nil
+}
@@ -0,0 +1,473 @@
+package tree
+
+import (
+	"sort"
+
+	"db/i18n"
+)
+
+// DefaultBTreeDegree минимальная степень B-дерева, используемая по умолчанию
+const DefaultBTreeDegree = 16
+
+// BTreeNode представляет собой узел B-дерева
+type BTreeNode struct {
+	keys     []string
+	values   []interface{}
+	children []*BTreeNode
+	leaf     bool
+}
+
+// BTree представляет собой B-дерево минимальной степени t:
+// каждый узел, кроме корня, хранит от t-1 до 2t-1 ключей
+type BTree struct {
+	root *BTreeNode
+	t    int
+}
+
+// NewBTree создает новое пустое B-дерево минимальной степени t (t >= 2)
+func NewBTree(t int) (*BTree, error) {
+	if t < 2 {
+		return nil, i18n.Errorf(i18n.BTreeDegreeTooSmall, t)
+	}
+	return &BTree{root: &BTreeNode{leaf: true}, t: t}, nil
+}
+
+// Degree возвращает минимальную степень дерева
+func (bt *BTree) Degree() int {
+	return bt.t
+}
+
+// Insert вставляет новый ключ со значением в дерево
+func (bt *BTree) Insert(key string, value interface{}) error {
+	if _, _, found := bt.root.find(key); found {
+		return ErrDuplicateKey
+	}
+	root := bt.root
+	if len(root.keys) == 2*bt.t-1 {
+		newRoot := &BTreeNode{children: []*BTreeNode{root}}
+		bt.splitChild(newRoot, 0)
+		bt.root = newRoot
+	}
+	bt.insertNonFull(bt.root, key, value)
+	return nil
+}
+
+// Get возвращает значение, связанное с заданным ключом
+func (bt *BTree) Get(key string) (interface{}, error) {
+	node, i, found := bt.root.find(key)
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+	return node.values[i], nil
+}
+
+// GetRange возвращает список ключей в заданном диапазоне значений
+func (bt *BTree) GetRange(minValue, maxValue string) ([]string, error) {
+	var result []string
+	bt.root.walkRange(minValue, maxValue, func(key string, value interface{}) bool {
+		result = append(result, key)
+		return true
+	})
+	return result, nil
+}
+
+// GetRangeRecords возвращает пары ключ-значение в заданном диапазоне значений
+func (bt *BTree) GetRangeRecords(minValue, maxValue string) ([]Record, error) {
+	var result []Record
+	bt.root.walkRange(minValue, maxValue, func(key string, value interface{}) bool {
+		result = append(result, Record{Key: key, Value: value})
+		return true
+	})
+	return result, nil
+}
+
+// Update обновляет значение, связанное с заданным ключом
+func (bt *BTree) Update(key string, value interface{}) error {
+	node, i, found := bt.root.find(key)
+	if !found {
+		return ErrKeyNotFound
+	}
+	node.values[i] = value
+	return nil
+}
+
+// Remove удаляет ключ из дерева
+func (bt *BTree) Remove(key string) error {
+	if _, _, found := bt.root.find(key); !found {
+		return ErrKeyNotFound
+	}
+	bt.remove(bt.root, key)
+	if len(bt.root.keys) == 0 && !bt.root.leaf {
+		bt.root = bt.root.children[0]
+	}
+	return nil
+}
+
+// ForEach обходит ключи дерева в порядке возрастания, пока fn возвращает true
+func (bt *BTree) ForEach(fn func(key string, value interface{}) bool) {
+	bt.root.walk(fn)
+}
+
+// bTreeFrame позиция курсора в узле B-дерева: для текущего (последнего) узла
+// пути index — номер ключа, для предков — номер потомка, в который выполнен спуск
+type bTreeFrame struct {
+	node  *BTreeNode
+	index int
+}
+
+// bTreeCursor курсор по B-дереву, хранящий путь от корня до текущего ключа
+type bTreeCursor struct {
+	tree *BTree
+	path []bTreeFrame
+}
+
+// Cursor возвращает курсор по дереву
+func (bt *BTree) Cursor() Cursor {
+	return &bTreeCursor{tree: bt}
+}
+
+func (c *bTreeCursor) Seek(key string) bool {
+	c.path = c.path[:0]
+	node := c.tree.root
+	for {
+		i := node.search(key)
+		c.path = append(c.path, bTreeFrame{node, i})
+		if i < len(node.keys) && node.keys[i] == key {
+			return true
+		}
+		if node.leaf {
+			if i < len(node.keys) {
+				return true
+			}
+			return c.ascendNext()
+		}
+		node = node.children[i]
+	}
+}
+
+func (c *bTreeCursor) First() bool {
+	c.path = c.path[:0]
+	c.descend(c.tree.root, true)
+	return c.Valid()
+}
+
+func (c *bTreeCursor) Last() bool {
+	c.path = c.path[:0]
+	c.descend(c.tree.root, false)
+	return c.Valid()
+}
+
+// descend спускается от узла до крайнего левого (left) или правого ключа поддерева
+func (c *bTreeCursor) descend(node *BTreeNode, left bool) {
+	for {
+		index := 0
+		if !left {
+			index = len(node.children) - 1
+			if node.leaf {
+				index = len(node.keys) - 1
+			}
+		}
+		c.path = append(c.path, bTreeFrame{node, index})
+		if node.leaf {
+			return
+		}
+		node = node.children[index]
+	}
+}
+
+func (c *bTreeCursor) Next() bool {
+	if !c.Valid() {
+		return false
+	}
+	top := &c.path[len(c.path)-1]
+	if !top.node.leaf {
+		top.index++
+		c.descend(top.node.children[top.index], true)
+		return true
+	}
+	top.index++
+	if top.index < len(top.node.keys) {
+		return true
+	}
+	return c.ascendNext()
+}
+
+// ascendNext поднимается к предку, у которого есть ключ правее пройденного потомка
+func (c *bTreeCursor) ascendNext() bool {
+	for {
+		c.path = c.path[:len(c.path)-1]
+		if len(c.path) == 0 {
+			return false
+		}
+		top := c.path[len(c.path)-1]
+		if top.index < len(top.node.keys) {
+			return true
+		}
+	}
+}
+
+func (c *bTreeCursor) Prev() bool {
+	if !c.Valid() {
+		return false
+	}
+	top := &c.path[len(c.path)-1]
+	if !top.node.leaf {
+		c.descend(top.node.children[top.index], false)
+		return true
+	}
+	top.index--
+	if top.index >= 0 {
+		return true
+	}
+	// Поднимаемся к предку, у которого есть ключ левее пройденного потомка
+	for {
+		c.path = c.path[:len(c.path)-1]
+		if len(c.path) == 0 {
+			return false
+		}
+		top := &c.path[len(c.path)-1]
+		if top.index > 0 {
+			top.index--
+			return true
+		}
+	}
+}
+
+func (c *bTreeCursor) Valid() bool {
+	if len(c.path) == 0 {
+		return false
+	}
+	top := c.path[len(c.path)-1]
+	return top.index >= 0 && top.index < len(top.node.keys)
+}
+
+func (c *bTreeCursor) Key() string {
+	top := c.path[len(c.path)-1]
+	return top.node.keys[top.index]
+}
+
+func (c *bTreeCursor) Value() interface{} {
+	top := c.path[len(c.path)-1]
+	return top.node.values[top.index]
+}
+
+func (c *bTreeCursor) Close() {
+	c.path = nil
+}
+
+// search возвращает позицию первого ключа узла, не меньшего key
+func (node *BTreeNode) search(key string) int {
+	return sort.SearchStrings(node.keys, key)
+}
+
+// find ищет ключ в поддереве и возвращает узел и позицию ключа в нем
+func (node *BTreeNode) find(key string) (*BTreeNode, int, bool) {
+	for {
+		i := node.search(key)
+		if i < len(node.keys) && node.keys[i] == key {
+			return node, i, true
+		}
+		if node.leaf {
+			return nil, 0, false
+		}
+		node = node.children[i]
+	}
+}
+
+// walk выполняет симметричный обход поддерева; возвращает false, если обход прерван
+func (node *BTreeNode) walk(fn func(key string, value interface{}) bool) bool {
+	for i := range node.keys {
+		if !node.leaf && !node.children[i].walk(fn) {
+			return false
+		}
+		if !fn(node.keys[i], node.values[i]) {
+			return false
+		}
+	}
+	if !node.leaf {
+		return node.children[len(node.keys)].walk(fn)
+	}
+	return true
+}
+
+// walkRange обходит по порядку ключи поддерева из диапазона [minValue, maxValue],
+// пропуская поддеревья, целиком лежащие вне диапазона
+func (node *BTreeNode) walkRange(minValue, maxValue string, fn func(key string, value interface{}) bool) bool {
+	i := node.search(minValue)
+	for ; i < len(node.keys); i++ {
+		if !node.leaf && !node.children[i].walkRange(minValue, maxValue, fn) {
+			return false
+		}
+		if node.keys[i] > maxValue {
+			return false
+		}
+		if !fn(node.keys[i], node.values[i]) {
+			return false
+		}
+	}
+	if !node.leaf {
+		return node.children[i].walkRange(minValue, maxValue, fn)
+	}
+	return true
+}
+
+// splitChild разделяет заполненного потомка parent.children[i] на два узла,
+// поднимая средний ключ в parent
+func (bt *BTree) splitChild(parent *BTreeNode, i int) {
+	t := bt.t
+	child := parent.children[i]
+	sibling := &BTreeNode{leaf: child.leaf}
+
+	midKey, midValue := child.keys[t-1], child.values[t-1]
+	sibling.keys = append([]string(nil), child.keys[t:]...)
+	sibling.values = append([]interface{}(nil), child.values[t:]...)
+	child.keys = child.keys[:t-1]
+	child.values = child.values[:t-1]
+	if !child.leaf {
+		sibling.children = append([]*BTreeNode(nil), child.children[t:]...)
+		child.children = child.children[:t]
+	}
+
+	parent.keys = insertAt(parent.keys, i, midKey)
+	parent.values = insertAt(parent.values, i, midValue)
+	parent.children = insertAt(parent.children, i+1, sibling)
+}
+
+// insertNonFull вставляет ключ в поддерево с незаполненным корнем
+func (bt *BTree) insertNonFull(node *BTreeNode, key string, value interface{}) {
+	for {
+		i := node.search(key)
+		if node.leaf {
+			node.keys = insertAt(node.keys, i, key)
+			node.values = insertAt(node.values, i, value)
+			return
+		}
+		if len(node.children[i].keys) == 2*bt.t-1 {
+			bt.splitChild(node, i)
+			if key > node.keys[i] {
+				i++
+			}
+		}
+		node = node.children[i]
+	}
+}
+
+// remove удаляет ключ из поддерева, гарантируя перед спуском,
+// что в потомке не меньше t ключей
+func (bt *BTree) remove(node *BTreeNode, key string) {
+	t := bt.t
+	i := node.search(key)
+	if i < len(node.keys) && node.keys[i] == key {
+		if node.leaf {
+			node.keys = removeAt(node.keys, i)
+			node.values = removeAt(node.values, i)
+			return
+		}
+		left, right := node.children[i], node.children[i+1]
+		switch {
+		case len(left.keys) >= t:
+			predKey, predValue := left.max()
+			node.keys[i], node.values[i] = predKey, predValue
+			bt.remove(left, predKey)
+		case len(right.keys) >= t:
+			succKey, succValue := right.min()
+			node.keys[i], node.values[i] = succKey, succValue
+			bt.remove(right, succKey)
+		default:
+			bt.merge(node, i)
+			bt.remove(left, key)
+		}
+		return
+	}
+	if node.leaf {
+		return
+	}
+	if len(node.children[i].keys) < t {
+		i = bt.fill(node, i)
+	}
+	bt.remove(node.children[i], key)
+}
+
+// fill пополняет потомка node.children[i], у которого t-1 ключей, заимствуя ключ
+// у соседа или сливая его с соседом; возвращает индекс потомка для спуска
+func (bt *BTree) fill(node *BTreeNode, i int) int {
+	t := bt.t
+	switch {
+	case i > 0 && len(node.children[i-1].keys) >= t:
+		child, left := node.children[i], node.children[i-1]
+		child.keys = insertAt(child.keys, 0, node.keys[i-1])
+		child.values = insertAt(child.values, 0, node.values[i-1])
+		last := len(left.keys) - 1
+		node.keys[i-1], node.values[i-1] = left.keys[last], left.values[last]
+		left.keys, left.values = left.keys[:last], left.values[:last]
+		if !left.leaf {
+			child.children = insertAt(child.children, 0, left.children[last+1])
+			left.children = left.children[:last+1]
+		}
+		return i
+	case i < len(node.keys) && len(node.children[i+1].keys) >= t:
+		child, right := node.children[i], node.children[i+1]
+		child.keys = append(child.keys, node.keys[i])
+		child.values = append(child.values, node.values[i])
+		node.keys[i], node.values[i] = right.keys[0], right.values[0]
+		right.keys, right.values = removeAt(right.keys, 0), removeAt(right.values, 0)
+		if !right.leaf {
+			child.children = append(child.children, right.children[0])
+			right.children = removeAt(right.children, 0)
+		}
+		return i
+	case i < len(node.keys):
+		bt.merge(node, i)
+		return i
+	default:
+		bt.merge(node, i-1)
+		return i - 1
+	}
+}
+
+// merge сливает node.children[i+1] и ключ node.keys[i] в node.children[i]
+func (bt *BTree) merge(node *BTreeNode, i int) {
+	left, right := node.children[i], node.children[i+1]
+	left.keys = append(append(left.keys, node.keys[i]), right.keys...)
+	left.values = append(append(left.values, node.values[i]), right.values...)
+	if !left.leaf {
+		left.children = append(left.children, right.children...)
+	}
+	node.keys = removeAt(node.keys, i)
+	node.values = removeAt(node.values, i)
+	node.children = removeAt(node.children, i+1)
+}
+
+// min возвращает минимальный ключ поддерева и его значение
+func (node *BTreeNode) min() (string, interface{}) {
+	for !node.leaf {
+		node = node.children[0]
+	}
+	return node.keys[0], node.values[0]
+}
+
+// max возвращает максимальный ключ поддерева и его значение
+func (node *BTreeNode) max() (string, interface{}) {
+	for !node.leaf {
+		node = node.children[len(node.children)-1]
+	}
+	last := len(node.keys) - 1
+	return node.keys[last], node.values[last]
+}
+
+// insertAt вставляет элемент в срез на позицию i
+func insertAt[T any](s []T, i int, v T) []T {
+	var zero T
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+// removeAt удаляет элемент среза на позиции i
+func removeAt[T any](s []T, i int) []T {
+	copy(s[i:], s[i+1:])
+	var zero T
+	s[len(s)-1] = zero
+	return s[:len(s)-1]
+}
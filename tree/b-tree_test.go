@@ -0,0 +1,215 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// checkBTree проверяет свойства B-дерева: ключи узлов упорядочены и лежат
+// между ключами родителя, у узлов кроме корня от t-1 до 2t-1 ключей, у
+// внутренних узлов на одного потомка больше, чем ключей, и все листья на
+// одной глубине.
+func checkBTree(t *testing.T, bt *BTree) {
+	t.Helper()
+	leafDepth := -1
+	var check func(node *BTreeNode, depth int, lo, hi *string)
+	check = func(node *BTreeNode, depth int, lo, hi *string) {
+		count := len(node.keys)
+		if count > 2*bt.t-1 || (node != bt.root && count < bt.t-1) || (!node.leaf && count == 0) {
+			t.Fatalf("узел %v: %d ключей при t=%d", node.keys, count, bt.t)
+		}
+		if len(node.values) != count {
+			t.Fatalf("узел %v: %d значений", node.keys, len(node.values))
+		}
+		for i, key := range node.keys {
+			if (i > 0 && key <= node.keys[i-1]) || (lo != nil && key <= *lo) || (hi != nil && key >= *hi) {
+				t.Fatalf("узел %v: ключ %q нарушает порядок", node.keys, key)
+			}
+		}
+		if node.leaf {
+			if len(node.children) != 0 {
+				t.Fatalf("лист %v имеет потомков", node.keys)
+			}
+			if leafDepth < 0 {
+				leafDepth = depth
+			} else if depth != leafDepth {
+				t.Fatalf("лист %v на глубине %d, ожидалась %d", node.keys, depth, leafDepth)
+			}
+			return
+		}
+		if len(node.children) != count+1 {
+			t.Fatalf("узел %v: %d потомков", node.keys, len(node.children))
+		}
+		for i, child := range node.children {
+			childLo, childHi := lo, hi
+			if i > 0 {
+				childLo = &node.keys[i-1]
+			}
+			if i < count {
+				childHi = &node.keys[i]
+			}
+			check(child, depth+1, childLo, childHi)
+		}
+	}
+	check(bt.root, 0, nil, nil)
+}
+
+// shape записывает структуру поддерева: лист — ключи в скобках,
+// внутренний узел — потомки вперемежку с ключами.
+func (node *BTreeNode) shape() string {
+	parts := make([]string, 0, 2*len(node.keys)+1)
+	for i, key := range node.keys {
+		if !node.leaf {
+			parts = append(parts, node.children[i].shape())
+		}
+		parts = append(parts, key)
+	}
+	if !node.leaf {
+		parts = append(parts, node.children[len(node.keys)].shape())
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func TestNewBTreeDegree(t *testing.T) {
+	for _, degree := range []int{-1, 0, 1} {
+		if _, err := NewBTree(degree); err == nil {
+			t.Errorf("создано B-дерево степени %d", degree)
+		}
+	}
+	if _, err := NewBTreeCollection(1); err == nil {
+		t.Error("создана коллекция на B-дереве степени 1")
+	}
+	bt, err := NewBTree(2)
+	if err != nil || bt.Degree() != 2 {
+		t.Fatalf("NewBTree(2) = %v, %v", bt, err)
+	}
+}
+
+// Случаи удаления из B-дерева по Кормену: 1 — удаление из листа, 2a/2b — замена
+// ключа внутреннего узла предшественником или преемником, 2c — слияние потомков
+// вокруг удаляемого ключа, 3a — заимствование ключа у соседа перед спуском,
+// 3b — слияние с соседом перед спуском. Слияние единственного ключа корня
+// уменьшает высоту дерева.
+func TestBTreeRemoveCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		degree int
+		insert string
+		remove string
+		before string
+		after  string
+	}{
+		{"1 лист", 2, "abcd", "d", "[[a] b [c d]]", "[[a] b [c]]"},
+		{"2a предшественник", 2, "dcba", "c", "[[a b] c [d]]", "[[a] b [d]]"},
+		{"2b преемник", 2, "abcd", "b", "[[a] b [c d]]", "[[a] c [d]]"},
+		{"2c слияние и сжатие корня", 2, "abc", "b", "[a b c]", "[a c]"},
+		{"3a заимствование справа", 2, "abcd", "a", "[[a] b [c d]]", "[[b] c [d]]"},
+		{"3a заимствование слева", 2, "dcba", "d", "[[a b] c [d]]", "[[a] b [c]]"},
+
+		{"1 лист t=3", 3, "abcdef", "f", "[[a b] c [d e f]]", "[[a b] c [d e]]"},
+		{"2a предшественник t=3", 3, "fedcba", "d", "[[a b c] d [e f]]", "[[a b] c [e f]]"},
+		{"2b преемник t=3", 3, "abcdef", "c", "[[a b] c [d e f]]", "[[a b] d [e f]]"},
+		{"3a заимствование справа t=3", 3, "abcdef", "a", "[[a b] c [d e f]]", "[[b c] d [e f]]"},
+		{"3a заимствование слева t=3", 3, "fedcba", "f", "[[a b c] d [e f]]", "[[a b] c [d e]]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bt, _ := NewBTree(test.degree)
+			for _, key := range test.insert {
+				if err := bt.Insert(string(key), nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if shape := bt.root.shape(); shape != test.before {
+				t.Fatalf("до удаления %s, ожидалось %s", shape, test.before)
+			}
+			if err := bt.Remove(test.remove); err != nil {
+				t.Fatal(err)
+			}
+			checkBTree(t, bt)
+			if shape := bt.root.shape(); shape != test.after {
+				t.Fatalf("после удаления %s %s, ожидалось %s", test.remove, shape, test.after)
+			}
+		})
+	}
+}
+
+func TestBTreeMergeShrinksRoot(t *testing.T) {
+	for _, test := range []struct {
+		degree int
+		remove []string
+		shapes []string
+	}{
+		// 3b: у потомка и соседа по t-1 ключей, они сливаются с ключом корня
+		{2, []string{"d", "a"}, []string{"[[a] b [c]]", "[b c]"}},
+		// 2c: удаляемый ключ корня между потомками с t-1 ключами
+		{2, []string{"d", "b"}, []string{"[[a] b [c]]", "[a c]"}},
+		{3, []string{"f", "c"}, []string{"[[a b] c [d e]]", "[a b d e]"}},
+		{3, []string{"f", "a"}, []string{"[[a b] c [d e]]", "[b c d e]"}},
+	} {
+		bt, _ := NewBTree(test.degree)
+		for _, key := range "abcdef"[:2*test.degree] {
+			bt.Insert(string(key), nil)
+		}
+		for i, key := range test.remove {
+			if err := bt.Remove(key); err != nil {
+				t.Fatal(err)
+			}
+			checkBTree(t, bt)
+			if shape := bt.root.shape(); shape != test.shapes[i] {
+				t.Fatalf("t=%d, после удаления %v: %s, ожидалось %s", test.degree, test.remove[:i+1], shape, test.shapes[i])
+			}
+		}
+		if !bt.root.leaf {
+			t.Fatalf("t=%d: корень не стал листом", test.degree)
+		}
+	}
+}
+
+// removeOrders порядки удаления n ключей: i-й удаляемый ключ имеет номер order(n, i).
+var removeOrders = map[string]func(n, i int) int{
+	"по возрастанию": func(n, i int) int { return i },
+	"по убыванию":    func(n, i int) int { return n - 1 - i },
+	"от середины": func(n, i int) int {
+		if i%2 == 0 {
+			return n/2 + i/2
+		}
+		return n/2 - 1 - i/2
+	},
+	"через шаг": func(n, i int) int {
+		if i < (n+1)/2 {
+			return 2 * i
+		}
+		return 2*(i-(n+1)/2) + 1
+	},
+}
+
+// Удаление всех ключей в разном порядке из деревьев разной высоты затрагивает
+// заимствование и слияние во внутренних узлах вместе с их потомками.
+func TestBTreeRemoveAllOrders(t *testing.T) {
+	for _, degree := range []int{2, 3} {
+		for _, n := range []int{1, 2, 7, 20, 64, 200} {
+			for name, order := range removeOrders {
+				bt, _ := NewBTree(degree)
+				for i := 0; i < n; i++ {
+					bt.Insert(fmt.Sprintf("k%03d", i), i)
+				}
+				checkBTree(t, bt)
+				for i := 0; i < n; i++ {
+					key := fmt.Sprintf("k%03d", order(n, i))
+					if err := bt.Remove(key); err != nil {
+						t.Fatalf("t=%d, n=%d, %s: Remove(%q): %v", degree, n, name, key, err)
+					}
+					checkBTree(t, bt)
+					if _, err := bt.Get(key); err == nil {
+						t.Fatalf("t=%d, n=%d, %s: ключ %q остался после удаления", degree, n, name, key)
+					}
+				}
+				if !bt.root.leaf || len(bt.root.keys) != 0 {
+					t.Fatalf("t=%d, n=%d, %s: после удаления всех ключей корень %s", degree, n, name, bt.root.shape())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package tree содержит деревья поиска (АВЛ, B-дерево, красно-черное дерево)
+// и коллекции на их основе, а также коллекцию на основе map.
+package tree
+
+import (
+	"sort"
+
+	"db/i18n"
+)
+
+// Ошибки операций над записями; проверяются через errors.Is.
+var (
+	ErrKeyNotFound  = i18n.Errorf(i18n.KeyNotFound)
+	ErrDuplicateKey = i18n.Errorf(i18n.DuplicateKey)
+)
+
+// Интерфейс для ассоциативного контейнера который производит операции над коллекцией.
+// Деревья реализуют тот же контракт, что и коллекции схемы.
+type Tree = Collection
+
+// Record пара ключ-значение, возвращаемая запросами по диапазону.
+type Record struct {
+	Key   string
+	Value interface{}
+}
+
+// Collection общий контракт для всех коллекций схемы (map, АВЛ-дерево и т.д.).
+// ForEach обходит записи в порядке возрастания ключей, пока fn возвращает true,
+// Cursor позволяет обходить их постранично и в обратном порядке.
+// GetRange и GetRangeRecords возвращают ключи диапазона [minValue, maxValue]
+// в порядке возрастания для любой реализации.
+type Collection interface {
+	Insert(key string, value interface{}) error
+	Get(key string) (interface{}, error)
+	GetRange(minValue, maxValue string) ([]string, error)
+	GetRangeRecords(minValue, maxValue string) ([]Record, error)
+	Update(key string, value interface{}) error
+	Remove(key string) error
+	ForEach(fn func(key string, value interface{}) bool)
+	Cursor() Cursor
+}
+
+// NewCollection создает коллекцию заданного типа: map, avl, btree или redblack.
+// Пустой тип означает map.
+func NewCollection(collectionType string) (Collection, error) {
+	switch collectionType {
+	case "", "map":
+		return NewMapCollection(), nil
+	case "avl", "btree", "redblack":
+		return NewTreeCollection(collectionType), nil
+	default:
+		return nil, i18n.Errorf(i18n.UnknownCollectionType, collectionType)
+	}
+}
+
+type TreeCollection struct {
+	tree Tree
+}
+
+func NewTreeCollection(treeType string) *TreeCollection {
+	var tree Tree
+	switch treeType {
+	case "avl":
+		tree = Tree(NewAVLTree())
+	case "btree":
+		tree, _ = NewBTree(DefaultBTreeDegree)
+	case "redblack":
+		tree = NewRedBlackTree()
+	default:
+		tree = NewAVLTree() // По умолчанию используем AVL-дерево
+	}
+	return &TreeCollection{tree: tree}
+}
+
+// Tree возвращает дерево, на котором построена коллекция.
+func (tc *TreeCollection) Tree() Tree {
+	return tc.tree
+}
+
+// NewBTreeCollection создает коллекцию на основе B-дерева минимальной степени t
+func NewBTreeCollection(t int) (*TreeCollection, error) {
+	tree, err := NewBTree(t)
+	if err != nil {
+		return nil, err
+	}
+	return &TreeCollection{tree: tree}, nil
+}
+
+func (tc *TreeCollection) Insert(key string, value interface{}) error {
+	return tc.tree.Insert(key, value)
+}
+
+func (tc *TreeCollection) Get(key string) (interface{}, error) {
+	return tc.tree.Get(key)
+}
+
+func (tc *TreeCollection) GetRange(minValue, maxValue string) ([]string, error) {
+	return tc.tree.GetRange(minValue, maxValue)
+}
+
+func (tc *TreeCollection) GetRangeRecords(minValue, maxValue string) ([]Record, error) {
+	return tc.tree.GetRangeRecords(minValue, maxValue)
+}
+
+func (tc *TreeCollection) Update(key string, value interface{}) error {
+	return tc.tree.Update(key, value)
+}
+
+func (tc *TreeCollection) Remove(key string) error {
+	return tc.tree.Remove(key)
+}
+
+func (tc *TreeCollection) ForEach(fn func(key string, value interface{}) bool) {
+	tc.tree.ForEach(fn)
+}
+
+func (tc *TreeCollection) Cursor() Cursor {
+	return tc.tree.Cursor()
+}
+
+// Пример реализации интерфейса Collection на основе map.
+type MapCollection struct {
+	data map[string]interface{}
+}
+
+func NewMapCollection() *MapCollection {
+	return &MapCollection{
+		data: make(map[string]interface{}),
+	}
+}
+
+func (mc *MapCollection) Insert(key string, value interface{}) error {
+	if _, exists := mc.data[key]; exists {
+		return ErrDuplicateKey
+	}
+	mc.data[key] = value
+	return nil
+}
+
+func (mc *MapCollection) Get(key string) (interface{}, error) {
+	value, exists := mc.data[key]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (mc *MapCollection) GetRange(minValue, maxValue string) ([]string, error) {
+	var result []string
+	for key := range mc.data {
+		if key >= minValue && key <= maxValue {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (mc *MapCollection) GetRangeRecords(minValue, maxValue string) ([]Record, error) {
+	var result []Record
+	for key, value := range mc.data {
+		if key >= minValue && key <= maxValue {
+			result = append(result, Record{Key: key, Value: value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+func (mc *MapCollection) Update(key string, value interface{}) error {
+	if _, exists := mc.data[key]; !exists {
+		return ErrKeyNotFound
+	}
+	mc.data[key] = value
+	return nil
+}
+
+func (mc *MapCollection) Remove(key string) error {
+	if _, exists := mc.data[key]; !exists {
+		return ErrKeyNotFound
+	}
+	delete(mc.data, key)
+	return nil
+}
+
+// ForEach обходит элементы в порядке возрастания ключей.
+func (mc *MapCollection) ForEach(fn func(key string, value interface{}) bool) {
+	keys := make([]string, 0, len(mc.data))
+	for key := range mc.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key, mc.data[key]) {
+			return
+		}
+	}
+}
+
+// Cursor возвращает курсор по ключам, отсортированным в момент его создания.
+func (mc *MapCollection) Cursor() Cursor {
+	keys := make([]string, 0, len(mc.data))
+	for key := range mc.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return NewSliceCursor(keys, func(key string) interface{} {
+		return mc.data[key]
+	})
+}
@@ -0,0 +1,199 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// testTrees создает по одному пустому дереву каждой реализации, включая
+// B-деревья минимальных степеней 2 и 3, на которых чаще всего срабатывают
+// заимствование ключей и слияние узлов.
+func testTrees(t *testing.T) map[string]Tree {
+	t.Helper()
+	trees := map[string]Tree{
+		"map":            NewMapCollection(),
+		"avl":            NewAVLTree(),
+		"avl-collection": NewAVLCollection(),
+		"btree":          NewTreeCollection("btree"),
+		"redblack":       NewRedBlackTree(),
+		"rb-collection":  NewTreeCollection("redblack"),
+	}
+	for _, degree := range []int{2, 3} {
+		bt, err := NewBTree(degree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trees[fmt.Sprintf("btree-%d", degree)] = bt
+	}
+	return trees
+}
+
+// checkInvariants проверяет структурные свойства дерева, если они известны для его типа.
+func checkInvariants(t *testing.T, tree Tree) {
+	t.Helper()
+	switch tree := tree.(type) {
+	case *TreeCollection:
+		checkInvariants(t, tree.Tree())
+	case *AVLTree:
+		checkAVL(t, tree)
+	case *BTree:
+		checkBTree(t, tree)
+	case *RedBlackTree:
+		checkRedBlack(t, tree)
+	}
+}
+
+// checkContents сравнивает содержимое дерева с эталонным map через все
+// способы чтения: Get, ForEach, GetRange, GetRangeRecords и обход курсором
+// в обе стороны.
+func checkContents(t *testing.T, tree Tree, model map[string]interface{}, rng *rand.Rand) {
+	t.Helper()
+	keys := make([]string, 0, len(model))
+	for key := range model {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if value, err := tree.Get(key); err != nil || value != model[key] {
+			t.Fatalf("Get(%q) = %v, %v; ожидалось %v", key, value, err, model[key])
+		}
+	}
+	var walked []string
+	tree.ForEach(func(key string, value interface{}) bool {
+		if value != model[key] {
+			t.Fatalf("ForEach: %q = %v, ожидалось %v", key, value, model[key])
+		}
+		walked = append(walked, key)
+		return true
+	})
+	if fmt.Sprint(walked) != fmt.Sprint(keys) {
+		t.Fatalf("ForEach: %v, ожидалось %v", walked, keys)
+	}
+
+	cursor := tree.Cursor()
+	walked = walked[:0]
+	for valid := cursor.First(); valid; valid = cursor.Next() {
+		walked = append(walked, cursor.Key())
+	}
+	if fmt.Sprint(walked) != fmt.Sprint(keys) {
+		t.Fatalf("курсор от начала: %v, ожидалось %v", walked, keys)
+	}
+	walked = walked[:0]
+	for valid := cursor.Last(); valid; valid = cursor.Prev() {
+		walked = append([]string{cursor.Key()}, walked...)
+	}
+	if fmt.Sprint(walked) != fmt.Sprint(keys) {
+		t.Fatalf("курсор от конца: %v, ожидалось %v", walked, keys)
+	}
+	cursor.Close()
+
+	// Диапазон со случайными границами, которые могут отсутствовать в дереве
+	minValue, maxValue := randomKey(rng), randomKey(rng)
+	var want []string
+	for _, key := range keys {
+		if key >= minValue && key <= maxValue {
+			want = append(want, key)
+		}
+	}
+	got, err := tree.GetRange(minValue, maxValue)
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("GetRange(%q, %q) = %v, %v; ожидалось %v", minValue, maxValue, got, err, want)
+	}
+	records, err := tree.GetRangeRecords(minValue, maxValue)
+	if err != nil || len(records) != len(want) {
+		t.Fatalf("GetRangeRecords(%q, %q) = %v, %v; ожидалось %v", minValue, maxValue, records, err, want)
+	}
+	for i, record := range records {
+		if record.Key != want[i] || record.Value != model[record.Key] {
+			t.Fatalf("GetRangeRecords(%q, %q)[%d] = %v", minValue, maxValue, i, record)
+		}
+	}
+}
+
+// randomKey выбирает ключ из небольшого множества, чтобы операции часто
+// попадали в существующие ключи.
+func randomKey(rng *rand.Rand) string {
+	return fmt.Sprintf("k%03d", rng.Intn(300))
+}
+
+func TestTreesMatchMap(t *testing.T) {
+	for name, tree := range testTrees(t) {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			model := make(map[string]interface{})
+			for step := 0; step < 20000; step++ {
+				key := randomKey(rng)
+				_, exists := model[key]
+				switch op := rng.Intn(10); {
+				case op < 4:
+					err := tree.Insert(key, step)
+					if exists != errors.Is(err, ErrDuplicateKey) || (!exists && err != nil) {
+						t.Fatalf("шаг %d: Insert(%q) = %v, ключ существует: %v", step, key, err, exists)
+					}
+					if !exists {
+						model[key] = step
+					}
+				case op < 6:
+					err := tree.Update(key, step)
+					if exists == errors.Is(err, ErrKeyNotFound) || (exists && err != nil) {
+						t.Fatalf("шаг %d: Update(%q) = %v, ключ существует: %v", step, key, err, exists)
+					}
+					if exists {
+						model[key] = step
+					}
+				case op < 9:
+					err := tree.Remove(key)
+					if exists == errors.Is(err, ErrKeyNotFound) || (exists && err != nil) {
+						t.Fatalf("шаг %d: Remove(%q) = %v, ключ существует: %v", step, key, err, exists)
+					}
+					delete(model, key)
+				default:
+					value, err := tree.Get(key)
+					if exists == errors.Is(err, ErrKeyNotFound) || value != model[key] {
+						t.Fatalf("шаг %d: Get(%q) = %v, %v; ожидалось %v", step, key, value, err, model[key])
+					}
+				}
+				checkInvariants(t, tree)
+				if step%500 == 0 {
+					checkContents(t, tree, model, rng)
+				}
+			}
+			// Удаление всех ключей возвращает дерево в пустое состояние
+			for key := range model {
+				if err := tree.Remove(key); err != nil {
+					t.Fatalf("Remove(%q): %v", key, err)
+				}
+				delete(model, key)
+				checkInvariants(t, tree)
+			}
+			checkContents(t, tree, model, rng)
+		})
+	}
+}
+
+// checkAVL проверяет порядок ключей, сохраненные высоты и баланс узлов АВЛ-дерева.
+func checkAVL(t *testing.T, avl *AVLTree) {
+	t.Helper()
+	var check func(node *Node, lo, hi *string) int
+	check = func(node *Node, lo, hi *string) int {
+		if node == nil {
+			return 0
+		}
+		if (lo != nil && node.key <= *lo) || (hi != nil && node.key >= *hi) {
+			t.Fatalf("ключ %q нарушает порядок", node.key)
+		}
+		left, right := check(node.left, lo, &node.key), check(node.right, &node.key, hi)
+		if left-right > 1 || right-left > 1 {
+			t.Fatalf("узел %q несбалансирован: высоты поддеревьев %d и %d", node.key, left, right)
+		}
+		if node.height != 1+max(left, right) {
+			t.Fatalf("узел %q: высота %d, ожидалась %d", node.key, node.height, 1+max(left, right))
+		}
+		return node.height
+	}
+	check(avl.root, nil, nil)
+}
@@ -0,0 +1,90 @@
+package tree
+
+// Cursor упорядоченный курсор по записям дерева или коллекции.
+//
+// Seek, First и Last устанавливают курсор на запись, Next и Prev сдвигают его
+// к следующей или предыдущей записи в порядке ключей. Все они возвращают true,
+// если курсор указывает на запись. Key и Value допустимо вызывать, только пока
+// Valid возвращает true. Курсор действителен, пока коллекция не изменяется:
+// после изменения следует создать новый курсор и продолжить обход через Seek.
+type Cursor interface {
+	// Seek устанавливает курсор на первую запись с ключом, не меньшим key
+	Seek(key string) bool
+	First() bool
+	Last() bool
+	Next() bool
+	Prev() bool
+	Valid() bool
+	Key() string
+	Value() interface{}
+	// Close освобождает курсор; после Close курсор недействителен
+	Close()
+}
+
+// sliceCursor курсор по заранее отсортированному списку ключей.
+type sliceCursor struct {
+	keys  []string
+	get   func(key string) interface{}
+	index int
+}
+
+// NewSliceCursor создает курсор по отсортированному списку ключей; значения
+// запрашиваются у get при обращении к ним.
+func NewSliceCursor(keys []string, get func(key string) interface{}) Cursor {
+	return &sliceCursor{keys: keys, get: get, index: -1}
+}
+
+func (c *sliceCursor) Seek(key string) bool {
+	lo, hi := 0, len(c.keys)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if c.keys[mid] < key {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	c.index = lo
+	return c.Valid()
+}
+
+func (c *sliceCursor) First() bool {
+	c.index = 0
+	return c.Valid()
+}
+
+func (c *sliceCursor) Last() bool {
+	c.index = len(c.keys) - 1
+	return c.Valid()
+}
+
+func (c *sliceCursor) Next() bool {
+	if c.Valid() {
+		c.index++
+	}
+	return c.Valid()
+}
+
+func (c *sliceCursor) Prev() bool {
+	if c.Valid() {
+		c.index--
+	}
+	return c.Valid()
+}
+
+func (c *sliceCursor) Valid() bool {
+	return c.index >= 0 && c.index < len(c.keys)
+}
+
+func (c *sliceCursor) Key() string {
+	return c.keys[c.index]
+}
+
+func (c *sliceCursor) Value() interface{} {
+	return c.get(c.keys[c.index])
+}
+
+func (c *sliceCursor) Close() {
+	c.keys = nil
+	c.index = -1
+}
@@ -0,0 +1,141 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// walk сдвигает курсор функцией step, пока он действителен, и возвращает
+// пройденные ключи, начиная с текущего.
+func walk(cursor Cursor, step func() bool) []string {
+	keys := []string{}
+	for valid := cursor.Valid(); valid; valid = step() {
+		keys = append(keys, cursor.Key())
+	}
+	return keys
+}
+
+func reversed(keys []string) []string {
+	result := make([]string, 0, len(keys))
+	for i := len(keys) - 1; i >= 0; i-- {
+		result = append(result, keys[i])
+	}
+	return result
+}
+
+func TestCursorSeekAndWalk(t *testing.T) {
+	// Четные ключи присутствуют в дереве, нечетные — нет; 30 ключей дают
+	// несколько уровней в B-дереве степени 2
+	var keys []string
+	for i := 2; i <= 60; i += 2 {
+		keys = append(keys, fmt.Sprintf("k%02d", i))
+	}
+	targets := []string{"", "a", "k", "k0", "k00", "k01", "k60", "k61", "k7", "z", "\xff"}
+	for i := 2; i <= 60; i++ {
+		targets = append(targets, fmt.Sprintf("k%02d", i))
+	}
+
+	for name, tree := range testTrees(t) {
+		t.Run(name, func(t *testing.T) {
+			// Вставка вразброс, чтобы порядок обхода не совпадал с порядком вставки
+			for i := range keys {
+				key := keys[(i*7)%len(keys)]
+				if err := tree.Insert(key, "v"+key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cursor := tree.Cursor()
+			defer cursor.Close()
+
+			for _, target := range targets {
+				start := sort.SearchStrings(keys, target)
+				valid := cursor.Seek(target)
+				if valid != (start < len(keys)) || cursor.Valid() != valid {
+					t.Fatalf("Seek(%q) = %v, ожидалось %v", target, valid, start < len(keys))
+				}
+				if !valid {
+					// Курсор за последним ключом не сдвигается ни в одну сторону
+					if cursor.Prev() || cursor.Next() || cursor.Valid() {
+						t.Fatalf("Seek(%q): курсор за концом сдвинулся", target)
+					}
+					continue
+				}
+				if cursor.Key() != keys[start] || cursor.Value() != "v"+keys[start] {
+					t.Fatalf("Seek(%q) указывает на %q = %v, ожидалось %q", target, cursor.Key(), cursor.Value(), keys[start])
+				}
+				if got := walk(cursor, cursor.Next); fmt.Sprint(got) != fmt.Sprint(keys[start:]) {
+					t.Fatalf("Seek(%q) и Next: %v, ожидалось %v", target, got, keys[start:])
+				}
+				if cursor.Next() || cursor.Prev() || cursor.Valid() {
+					t.Fatalf("Seek(%q): курсор после последнего ключа сдвинулся", target)
+				}
+
+				cursor.Seek(target)
+				if got, want := walk(cursor, cursor.Prev), reversed(keys[:start+1]); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("Seek(%q) и Prev: %v, ожидалось %v", target, got, want)
+				}
+				if cursor.Prev() || cursor.Next() || cursor.Valid() {
+					t.Fatalf("Seek(%q): курсор перед первым ключом сдвинулся", target)
+				}
+
+				// Смена направления возвращает курсор на исходный ключ
+				cursor.Seek(target)
+				if cursor.Prev() {
+					if !cursor.Next() || cursor.Key() != keys[start] {
+						t.Fatalf("Seek(%q), Prev и Next не вернули курсор на %q", target, keys[start])
+					}
+				}
+				cursor.Seek(target)
+				if cursor.Next() {
+					if !cursor.Prev() || cursor.Key() != keys[start] {
+						t.Fatalf("Seek(%q), Next и Prev не вернули курсор на %q", target, keys[start])
+					}
+				}
+			}
+
+			if !cursor.First() || fmt.Sprint(walk(cursor, cursor.Next)) != fmt.Sprint(keys) {
+				t.Fatal("обход от First не совпадает с ключами дерева")
+			}
+			if !cursor.Last() || fmt.Sprint(walk(cursor, cursor.Prev)) != fmt.Sprint(reversed(keys)) {
+				t.Fatal("обход от Last не совпадает с ключами дерева в обратном порядке")
+			}
+			cursor.First()
+			cursor.Close()
+			if cursor.Valid() {
+				t.Fatal("курсор действителен после Close")
+			}
+		})
+	}
+}
+
+func TestCursorSmallTrees(t *testing.T) {
+	for name, tree := range testTrees(t) {
+		t.Run(name, func(t *testing.T) {
+			cursor := tree.Cursor()
+			if cursor.First() || cursor.Last() || cursor.Seek("") || cursor.Valid() || cursor.Next() || cursor.Prev() {
+				t.Fatal("курсор по пустому дереву указывает на запись")
+			}
+
+			tree.Insert("m", 1)
+			cursor = tree.Cursor()
+			for _, position := range []func() bool{cursor.First, cursor.Last, func() bool { return cursor.Seek("m") },
+				func() bool { return cursor.Seek("a") }} {
+				if !position() || cursor.Key() != "m" || cursor.Value() != 1 {
+					t.Fatal("курсор не указывает на единственную запись")
+				}
+			}
+			if cursor.Seek("n") {
+				t.Fatal("Seek за единственной записью указывает на запись")
+			}
+			cursor.First()
+			if cursor.Next() || cursor.Valid() {
+				t.Fatal("Next после единственной записи указывает на запись")
+			}
+			cursor.Last()
+			if cursor.Prev() || cursor.Valid() {
+				t.Fatal("Prev перед единственной записью указывает на запись")
+			}
+		})
+	}
+}
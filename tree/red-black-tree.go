@@ -0,0 +1,433 @@
+package tree
+
+// color цвет узла красно-черного дерева
+type color bool
+
+const (
+	red   color = true
+	black color = false
+)
+
+// RBNode представляет собой узел красно-черного дерева
+type RBNode struct {
+	key    string
+	value  interface{}
+	color  color
+	left   *RBNode
+	right  *RBNode
+	parent *RBNode
+}
+
+// RedBlackTree представляет собой структуру красно-черного дерева.
+// Вместо nil-листьев используется общий черный узел-страж sentinel.
+type RedBlackTree struct {
+	root     *RBNode
+	sentinel *RBNode
+}
+
+// NewRedBlackTree создает новое пустое красно-черное дерево
+func NewRedBlackTree() *RedBlackTree {
+	sentinel := &RBNode{color: black}
+	return &RedBlackTree{root: sentinel, sentinel: sentinel}
+}
+
+// Insert вставляет новый ключ со значением в дерево
+func (rb *RedBlackTree) Insert(key string, value interface{}) error {
+	parent := rb.sentinel
+	current := rb.root
+	for current != rb.sentinel {
+		parent = current
+		if key < current.key {
+			current = current.left
+		} else if key > current.key {
+			current = current.right
+		} else {
+			return ErrDuplicateKey
+		}
+	}
+
+	node := &RBNode{key: key, value: value, color: red, left: rb.sentinel, right: rb.sentinel, parent: parent}
+	if parent == rb.sentinel {
+		rb.root = node
+	} else if key < parent.key {
+		parent.left = node
+	} else {
+		parent.right = node
+	}
+	rb.insertFixup(node)
+	return nil
+}
+
+// Get возвращает значение, связанное с заданным ключом
+func (rb *RedBlackTree) Get(key string) (interface{}, error) {
+	node := rb.getNode(key)
+	if node == rb.sentinel {
+		return nil, ErrKeyNotFound
+	}
+	return node.value, nil
+}
+
+// GetRange возвращает список ключей в заданном диапазоне значений
+func (rb *RedBlackTree) GetRange(minValue, maxValue string) ([]string, error) {
+	var result []string
+	rb.walkRange(rb.root, minValue, maxValue, func(node *RBNode) {
+		result = append(result, node.key)
+	})
+	return result, nil
+}
+
+// GetRangeRecords возвращает пары ключ-значение в заданном диапазоне значений
+func (rb *RedBlackTree) GetRangeRecords(minValue, maxValue string) ([]Record, error) {
+	var result []Record
+	rb.walkRange(rb.root, minValue, maxValue, func(node *RBNode) {
+		result = append(result, Record{Key: node.key, Value: node.value})
+	})
+	return result, nil
+}
+
+// Update обновляет значение, связанное с заданным ключом
+func (rb *RedBlackTree) Update(key string, value interface{}) error {
+	node := rb.getNode(key)
+	if node == rb.sentinel {
+		return ErrKeyNotFound
+	}
+	node.value = value
+	return nil
+}
+
+// Remove удаляет узел с заданным ключом из дерева
+func (rb *RedBlackTree) Remove(key string) error {
+	node := rb.getNode(key)
+	if node == rb.sentinel {
+		return ErrKeyNotFound
+	}
+
+	removed := node
+	removedColor := removed.color
+	var fixup *RBNode
+	if node.left == rb.sentinel {
+		fixup = node.right
+		rb.transplant(node, node.right)
+	} else if node.right == rb.sentinel {
+		fixup = node.left
+		rb.transplant(node, node.left)
+	} else {
+		removed = rb.minimum(node.right)
+		removedColor = removed.color
+		fixup = removed.right
+		if removed.parent == node {
+			fixup.parent = removed
+		} else {
+			rb.transplant(removed, removed.right)
+			removed.right = node.right
+			removed.right.parent = removed
+		}
+		rb.transplant(node, removed)
+		removed.left = node.left
+		removed.left.parent = removed
+		removed.color = node.color
+	}
+	if removedColor == black {
+		rb.deleteFixup(fixup)
+	}
+	return nil
+}
+
+// ForEach обходит узлы дерева в порядке возрастания ключей, пока fn возвращает true
+func (rb *RedBlackTree) ForEach(fn func(key string, value interface{}) bool) {
+	var walk func(node *RBNode) bool
+	walk = func(node *RBNode) bool {
+		if node == rb.sentinel {
+			return true
+		}
+		return walk(node.left) && fn(node.key, node.value) && walk(node.right)
+	}
+	walk(rb.root)
+}
+
+// walkRange обходит по порядку узлы поддерева с ключами из диапазона [minValue, maxValue]
+func (rb *RedBlackTree) walkRange(node *RBNode, minValue, maxValue string, fn func(node *RBNode)) {
+	if node == rb.sentinel {
+		return
+	}
+	if node.key > minValue {
+		rb.walkRange(node.left, minValue, maxValue, fn)
+	}
+	if node.key >= minValue && node.key <= maxValue {
+		fn(node)
+	}
+	if node.key < maxValue {
+		rb.walkRange(node.right, minValue, maxValue, fn)
+	}
+}
+
+// getNode возвращает узел с данным ключом или узел-страж, если ключа нет
+func (rb *RedBlackTree) getNode(key string) *RBNode {
+	node := rb.root
+	for node != rb.sentinel && node.key != key {
+		if key < node.key {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	return node
+}
+
+// minimum возвращает узел с минимальным ключом в поддереве
+func (rb *RedBlackTree) minimum(node *RBNode) *RBNode {
+	for node.left != rb.sentinel {
+		node = node.left
+	}
+	return node
+}
+
+// maximum возвращает узел с максимальным ключом в поддереве
+func (rb *RedBlackTree) maximum(node *RBNode) *RBNode {
+	for node.right != rb.sentinel {
+		node = node.right
+	}
+	return node
+}
+
+// leftRotate выполняет левое вращение вокруг узла x
+func (rb *RedBlackTree) leftRotate(x *RBNode) {
+	y := x.right
+	x.right = y.left
+	if y.left != rb.sentinel {
+		y.left.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == rb.sentinel {
+		rb.root = y
+	} else if x == x.parent.left {
+		x.parent.left = y
+	} else {
+		x.parent.right = y
+	}
+	y.left = x
+	x.parent = y
+}
+
+// rightRotate выполняет правое вращение вокруг узла y
+func (rb *RedBlackTree) rightRotate(y *RBNode) {
+	x := y.left
+	y.left = x.right
+	if x.right != rb.sentinel {
+		x.right.parent = y
+	}
+	x.parent = y.parent
+	if y.parent == rb.sentinel {
+		rb.root = x
+	} else if y == y.parent.right {
+		y.parent.right = x
+	} else {
+		y.parent.left = x
+	}
+	x.right = y
+	y.parent = x
+}
+
+// insertFixup восстанавливает свойства красно-черного дерева после вставки
+func (rb *RedBlackTree) insertFixup(node *RBNode) {
+	for node.parent.color == red {
+		grandparent := node.parent.parent
+		if node.parent == grandparent.left {
+			uncle := grandparent.right
+			if uncle.color == red {
+				node.parent.color = black
+				uncle.color = black
+				grandparent.color = red
+				node = grandparent
+				continue
+			}
+			if node == node.parent.right {
+				node = node.parent
+				rb.leftRotate(node)
+			}
+			node.parent.color = black
+			node.parent.parent.color = red
+			rb.rightRotate(node.parent.parent)
+		} else {
+			uncle := grandparent.left
+			if uncle.color == red {
+				node.parent.color = black
+				uncle.color = black
+				grandparent.color = red
+				node = grandparent
+				continue
+			}
+			if node == node.parent.left {
+				node = node.parent
+				rb.rightRotate(node)
+			}
+			node.parent.color = black
+			node.parent.parent.color = red
+			rb.leftRotate(node.parent.parent)
+		}
+	}
+	rb.root.color = black
+}
+
+// transplant заменяет поддерево u поддеревом v
+func (rb *RedBlackTree) transplant(u, v *RBNode) {
+	if u.parent == rb.sentinel {
+		rb.root = v
+	} else if u == u.parent.left {
+		u.parent.left = v
+	} else {
+		u.parent.right = v
+	}
+	v.parent = u.parent
+}
+
+// deleteFixup восстанавливает свойства красно-черного дерева после удаления
+func (rb *RedBlackTree) deleteFixup(node *RBNode) {
+	for node != rb.root && node.color == black {
+		if node == node.parent.left {
+			sibling := node.parent.right
+			if sibling.color == red {
+				sibling.color = black
+				node.parent.color = red
+				rb.leftRotate(node.parent)
+				sibling = node.parent.right
+			}
+			if sibling.left.color == black && sibling.right.color == black {
+				sibling.color = red
+				node = node.parent
+				continue
+			}
+			if sibling.right.color == black {
+				sibling.left.color = black
+				sibling.color = red
+				rb.rightRotate(sibling)
+				sibling = node.parent.right
+			}
+			sibling.color = node.parent.color
+			node.parent.color = black
+			sibling.right.color = black
+			rb.leftRotate(node.parent)
+			node = rb.root
+		} else {
+			sibling := node.parent.left
+			if sibling.color == red {
+				sibling.color = black
+				node.parent.color = red
+				rb.rightRotate(node.parent)
+				sibling = node.parent.left
+			}
+			if sibling.left.color == black && sibling.right.color == black {
+				sibling.color = red
+				node = node.parent
+				continue
+			}
+			if sibling.left.color == black {
+				sibling.right.color = black
+				sibling.color = red
+				rb.leftRotate(sibling)
+				sibling = node.parent.left
+			}
+			sibling.color = node.parent.color
+			node.parent.color = black
+			sibling.left.color = black
+			rb.rightRotate(node.parent)
+			node = rb.root
+		}
+	}
+	node.color = black
+}
+
+// rbCursor курсор по красно-черному дереву, использующий ссылки на родителей.
+type rbCursor struct {
+	tree *RedBlackTree
+	node *RBNode
+}
+
+// Cursor возвращает курсор по дереву
+func (rb *RedBlackTree) Cursor() Cursor {
+	return &rbCursor{tree: rb, node: rb.sentinel}
+}
+
+func (c *rbCursor) Seek(key string) bool {
+	rb := c.tree
+	c.node = rb.sentinel
+	for node := rb.root; node != rb.sentinel; {
+		if key == node.key {
+			c.node = node
+			break
+		}
+		if key < node.key {
+			c.node = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	return c.Valid()
+}
+
+func (c *rbCursor) First() bool {
+	c.node = c.tree.sentinel
+	if c.tree.root != c.tree.sentinel {
+		c.node = c.tree.minimum(c.tree.root)
+	}
+	return c.Valid()
+}
+
+func (c *rbCursor) Last() bool {
+	c.node = c.tree.sentinel
+	if c.tree.root != c.tree.sentinel {
+		c.node = c.tree.maximum(c.tree.root)
+	}
+	return c.Valid()
+}
+
+func (c *rbCursor) Next() bool {
+	if !c.Valid() {
+		return false
+	}
+	rb := c.tree
+	if c.node.right != rb.sentinel {
+		c.node = rb.minimum(c.node.right)
+		return true
+	}
+	parent := c.node.parent
+	for parent != rb.sentinel && c.node == parent.right {
+		c.node, parent = parent, parent.parent
+	}
+	c.node = parent
+	return c.Valid()
+}
+
+func (c *rbCursor) Prev() bool {
+	if !c.Valid() {
+		return false
+	}
+	rb := c.tree
+	if c.node.left != rb.sentinel {
+		c.node = rb.maximum(c.node.left)
+		return true
+	}
+	parent := c.node.parent
+	for parent != rb.sentinel && c.node == parent.left {
+		c.node, parent = parent, parent.parent
+	}
+	c.node = parent
+	return c.Valid()
+}
+
+func (c *rbCursor) Valid() bool {
+	return c.node != c.tree.sentinel
+}
+
+func (c *rbCursor) Key() string {
+	return c.node.key
+}
+
+func (c *rbCursor) Value() interface{} {
+	return c.node.value
+}
+
+func (c *rbCursor) Close() {
+	c.node = c.tree.sentinel
+}
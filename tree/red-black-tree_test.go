@@ -0,0 +1,169 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// checkRedBlack проверяет свойства красно-черного дерева: корень и страж
+// черные, у красного узла черные потомки, на всех путях от корня до листьев
+// одинаковое число черных узлов, ключи упорядочены, ссылки на родителей верны.
+func checkRedBlack(t *testing.T, rb *RedBlackTree) {
+	t.Helper()
+	if rb.root.color != black || rb.sentinel.color != black {
+		t.Fatal("корень или страж красный")
+	}
+	if rb.root != rb.sentinel && rb.root.parent != rb.sentinel {
+		t.Fatalf("у корня %q есть родитель", rb.root.key)
+	}
+	var check func(node *RBNode, lo, hi *string) int
+	check = func(node *RBNode, lo, hi *string) int {
+		if node == rb.sentinel {
+			return 1
+		}
+		if (lo != nil && node.key <= *lo) || (hi != nil && node.key >= *hi) {
+			t.Fatalf("ключ %q нарушает порядок", node.key)
+		}
+		for _, child := range []*RBNode{node.left, node.right} {
+			if child == rb.sentinel {
+				continue
+			}
+			if child.parent != node {
+				t.Fatalf("у узла %q неверный родитель", child.key)
+			}
+			if node.color == red && child.color == red {
+				t.Fatalf("красные узлы %q и %q подряд", node.key, child.key)
+			}
+		}
+		left, right := check(node.left, lo, &node.key), check(node.right, &node.key, hi)
+		if left != right {
+			t.Fatalf("узел %q: черная высота поддеревьев %d и %d", node.key, left, right)
+		}
+		if node.color == black {
+			left++
+		}
+		return left
+	}
+	check(rb.root, nil, nil)
+}
+
+// buildRedBlack строит дерево по записи shape: узел — ключ, за которым
+// для красного узла следует "*", а затем, если есть потомки, "(левый правый)";
+// отсутствующий потомок обозначается "-".
+func buildRedBlack(shape string) *RedBlackTree {
+	rb := NewRedBlackTree()
+	pos := 0
+	var parse func(parent *RBNode) *RBNode
+	parse = func(parent *RBNode) *RBNode {
+		if shape[pos] == '-' {
+			pos++
+			return rb.sentinel
+		}
+		start := pos
+		for pos < len(shape) && !strings.ContainsRune("*( )", rune(shape[pos])) {
+			pos++
+		}
+		key := shape[start:pos]
+		node := &RBNode{key: key, value: key, color: black, left: rb.sentinel, right: rb.sentinel, parent: parent}
+		if pos < len(shape) && shape[pos] == '*' {
+			node.color = red
+			pos++
+		}
+		if pos < len(shape) && shape[pos] == '(' {
+			pos++
+			node.left = parse(node)
+			pos++
+			node.right = parse(node)
+			pos++
+		}
+		return node
+	}
+	rb.root = parse(rb.sentinel)
+	return rb
+}
+
+// shape записывает поддерево в формате buildRedBlack.
+func (rb *RedBlackTree) shape(node *RBNode) string {
+	if node == rb.sentinel {
+		return "-"
+	}
+	text := node.key
+	if node.color == red {
+		text += "*"
+	}
+	if node.left != rb.sentinel || node.right != rb.sentinel {
+		text += "(" + rb.shape(node.left) + " " + rb.shape(node.right) + ")"
+	}
+	return text
+}
+
+// Случаи восстановления после удаления черного узла по Кормену: 1 — брат
+// красный, 2 — брат черный с черными потомками, 3 — ближний племянник красный,
+// дальний черный, 4 — дальний племянник красный; каждый в двух зеркальных вариантах.
+func TestRedBlackDeleteFixup(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		remove string
+		after  string
+	}{
+		{"1 брат красный", "b(a d*(c e))", "a", "d(b(- c*) e)"},
+		{"1 брат красный, зеркально", "d(b*(a c) e)", "e", "b(a d(c* -))"},
+		{"2 черный брат, корень", "b(a c)", "a", "b(- c*)"},
+		{"2 черный брат, корень, зеркально", "b(a c)", "c", "b(a* -)"},
+		{"2 черный брат, красный родитель", "d(b*(a c) f)", "a", "d(b(- c*) f)"},
+		{"2 черный брат, красный родитель, зеркально", "b(a d*(c e))", "e", "b(a d(c* -))"},
+		{"3 ближний племянник красный", "b(a d(c* -))", "a", "c(b d)"},
+		{"3 ближний племянник красный, зеркально", "d(b(- c*) e)", "e", "c(b d)"},
+		{"4 дальний племянник красный", "b(a c(- d*))", "a", "c(b d)"},
+		{"4 дальний племянник красный, зеркально", "d(b(a* -) e)", "e", "b(a d)"},
+		{"4 при двух красных племянниках", "b(a d(c* e*))", "a", "d(b(- c*) e)"},
+		// Удаление узла с двумя потомками заменяет его преемником
+		{"преемник — правый потомок", "b(a c)", "b", "c(a* -)"},
+		{"преемник в глубине", "b(a e*(d(c* -) f))", "b", "c(a e*(d f))"},
+		{"красный лист", "b(a* c*)", "c", "b(a* -)"},
+		{"единственный узел", "a", "a", "-"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rb := buildRedBlack(test.before)
+			checkRedBlack(t, rb)
+			if shape := rb.shape(rb.root); shape != test.before {
+				t.Fatalf("построено %s, ожидалось %s", shape, test.before)
+			}
+			if err := rb.Remove(test.remove); err != nil {
+				t.Fatal(err)
+			}
+			checkRedBlack(t, rb)
+			if shape := rb.shape(rb.root); shape != test.after {
+				t.Fatalf("после удаления %s %s, ожидалось %s", test.remove, shape, test.after)
+			}
+		})
+	}
+}
+
+func TestRedBlackRemoveAllOrders(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 20, 64, 200} {
+		for name, order := range removeOrders {
+			rb := NewRedBlackTree()
+			for i := 0; i < n; i++ {
+				rb.Insert(fmt.Sprintf("k%03d", i), i)
+				checkRedBlack(t, rb)
+			}
+			for i := 0; i < n; i++ {
+				key := fmt.Sprintf("k%03d", order(n, i))
+				if err := rb.Remove(key); err != nil {
+					t.Fatalf("n=%d, %s: Remove(%q): %v", n, name, key, err)
+				}
+				checkRedBlack(t, rb)
+				if _, err := rb.Get(key); err == nil {
+					t.Fatalf("n=%d, %s: ключ %q остался после удаления", n, name, key)
+				}
+			}
+			if rb.root != rb.sentinel {
+				t.Fatalf("n=%d, %s: после удаления всех ключей корень %q", n, name, rb.root.key)
+			}
+		}
+	}
+}